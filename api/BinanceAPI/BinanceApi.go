@@ -26,22 +26,30 @@ const (
 )
 
 var (
-	ACCESS_KEY string       = ""
-	SECRET_KEY string       = ""
 	httpClient *http.Client = &http.Client{}
 )
 
+// Binance 使用一组API密钥访问币安的私有接口,各个实例的密钥互不影响
+type Binance struct {
+	accessKey,
+	secretKey string
+}
+
+func New(api_key, secret_key string) *Binance {
+	return &Binance{api_key, secret_key}
+}
+
 func init() {
 	//os.Setenv("HTTP_PROXY", "http://127.0.0.1:6667")
 	//os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
 }
 
-func buildParamsSigned(postForm *url.Values) error {
+func (bn *Binance) buildParamsSigned(postForm *url.Values) error {
 	postForm.Set("recvWindow", "6000000")
 	tonce := strconv.FormatInt(time.Now().UnixNano(), 10)[0:13]
 	postForm.Set("timestamp", tonce)
 	payload := postForm.Encode()
-	sign, _ := GetParamHmacSHA256Sign(SECRET_KEY, payload)
+	sign, _ := GetParamHmacSHA256Sign(bn.secretKey, payload)
 	postForm.Set("signature", sign)
 	return nil
 }
@@ -58,15 +66,15 @@ func GetDepth(size int, symbol string) (map[string]interface{}, error) {
 	return resp, err
 }
 
-func GetAccount() (map[string]interface{}, error) {
+func (bn *Binance) GetAccount() (map[string]interface{}, error) {
 	params := url.Values{}
-	buildParamsSigned(&params)
+	bn.buildParamsSigned(&params)
 	path := API_V3 + ACCOUNT_URI + params.Encode()
-	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": bn.accessKey})
 	return respmap, err
 }
 
-func placeOrder(amount, price string, symbol string, orderType, orderSide string) (map[string]interface{}, error) {
+func (bn *Binance) placeOrder(amount, price string, symbol string, orderType, orderSide string) (map[string]interface{}, error) {
 	path := API_V3 + ORDER_URI
 	params := url.Values{}
 	params.Set("symbol", symbol)
@@ -82,9 +90,9 @@ func placeOrder(amount, price string, symbol string, orderType, orderSide string
 		params.Set("price", price)
 	}
 
-	buildParamsSigned(&params)
+	bn.buildParamsSigned(&params)
 
-	resp, err := HttpPostForm2(httpClient, path, params, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	resp, err := HttpPostForm2(httpClient, path, params, map[string]string{"X-MBX-APIKEY": bn.accessKey})
 	//log.Println("resp:", string(resp), "err:", err)
 	if err != nil {
 		return nil, err
@@ -100,31 +108,31 @@ func placeOrder(amount, price string, symbol string, orderType, orderSide string
 	return respmap, nil
 }
 
-func LimitBuy(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "LIMIT", "BUY")
+func (bn *Binance) LimitBuy(amount, price string, symbol string) (map[string]interface{}, error) {
+	return bn.placeOrder(amount, price, symbol, "LIMIT", "BUY")
 }
 
-func LimitSell(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "LIMIT", "SELL")
+func (bn *Binance) LimitSell(amount, price string, symbol string) (map[string]interface{}, error) {
+	return bn.placeOrder(amount, price, symbol, "LIMIT", "SELL")
 }
 
-func MarketBuy(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "MARKET", "BUY")
+func (bn *Binance) MarketBuy(amount, price string, symbol string) (map[string]interface{}, error) {
+	return bn.placeOrder(amount, price, symbol, "MARKET", "BUY")
 }
 
-func MarketSell(amount, price string, symbol string) (map[string]interface{}, error) {
-	return placeOrder(amount, price, symbol, "MARKET", "SELL")
+func (bn *Binance) MarketSell(amount, price string, symbol string) (map[string]interface{}, error) {
+	return bn.placeOrder(amount, price, symbol, "MARKET", "SELL")
 }
 
-func CancelOrder(orderId string, symbol string) (bool, error) {
+func (bn *Binance) CancelOrder(orderId string, symbol string) (bool, error) {
 	path := API_V3 + ORDER_URI
 	params := url.Values{}
 	params.Set("symbol", symbol)
 	params.Set("orderId", orderId)
 
-	buildParamsSigned(&params)
+	bn.buildParamsSigned(&params)
 
-	resp, err := HttpDeleteForm(httpClient, path, params, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	resp, err := HttpDeleteForm(httpClient, path, params, map[string]string{"X-MBX-APIKEY": bn.accessKey})
 
 	//log.Println("resp:", string(resp), "err:", err)
 	if err != nil {
@@ -146,7 +154,7 @@ func CancelOrder(orderId string, symbol string) (bool, error) {
 	return true, nil
 }
 
-func GetOneOrder(orderId string, symbol string) (map[string]interface{}, error) {
+func (bn *Binance) GetOneOrder(orderId string, symbol string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Set("symbol", symbol)
 	if orderId != "" {
@@ -154,20 +162,20 @@ func GetOneOrder(orderId string, symbol string) (map[string]interface{}, error)
 	}
 	params.Set("orderId", orderId)
 
-	buildParamsSigned(&params)
+	bn.buildParamsSigned(&params)
 	path := API_V3 + ORDER_URI + params.Encode()
 
-	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	respmap, err := HttpGet2(httpClient, path, map[string]string{"X-MBX-APIKEY": bn.accessKey})
 	return respmap, err
 }
 
-func GetUnfinishOrders(symbol string) ([]interface{}, error) {
+func (bn *Binance) GetUnfinishOrders(symbol string) ([]interface{}, error) {
 	params := url.Values{}
 	params.Set("symbol", symbol)
 
-	buildParamsSigned(&params)
+	bn.buildParamsSigned(&params)
 	path := API_V3 + UNFINISHED_ORDERS_INFO + params.Encode()
 
-	respmap, err := HttpGet3(httpClient, path, map[string]string{"X-MBX-APIKEY": ACCESS_KEY})
+	respmap, err := HttpGet3(httpClient, path, map[string]string{"X-MBX-APIKEY": bn.accessKey})
 	return respmap, err
 }
@@ -1,11 +1,10 @@
-﻿package config
+package config
 
-// API KEY
-var (
-	ACCESS_KEY string = ""
-	SECRET_KEY string = ""
-	ACCOUNT_ID string = ""
-)
+// API KEY, 每个交易所实例使用自己的密钥
+type Key struct {
+	ACCESS_KEY string
+	SECRET_KEY string
+}
 
 // API请求地址, 不要带最后的/
 const (
@@ -163,10 +163,10 @@ func GetTimestamp() (r models.TimestampReturn, err error) {
 
 // 查询当前用户的所有账户, 根据包含的私钥查询
 // return: AccountsReturn对象
-func GetAccounts() (r models.AccountsReturn, err error) {
+func GetAccounts(key config.Key) (r models.AccountsReturn, err error) {
 	strRequest := "/v1/account/accounts"
 
-	jsonAccountsReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonAccountsReturn := untils.ApiKeyGet(key, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonAccountsReturn), &r)
 
 	return
@@ -175,10 +175,10 @@ func GetAccounts() (r models.AccountsReturn, err error) {
 // 根据账户ID查询账户余额
 // nAccountID: 账户ID, 不知道的话可以通过GetAccounts()获取, 可以只现货账户, C2C账户, 期货账户
 // return: BalanceReturn对象
-func GetAccountBalance(strAccountID string) (r models.BalanceReturn, err error) {
+func GetAccountBalance(key config.Key, strAccountID string) (r models.BalanceReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/account/accounts/%s/balance", strAccountID)
 
-	jsonBanlanceReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonBanlanceReturn := untils.ApiKeyGet(key, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonBanlanceReturn), &r)
 
 	return
@@ -190,7 +190,7 @@ func GetAccountBalance(strAccountID string) (r models.BalanceReturn, err error)
 // 下单
 // params: 下单信息
 // return: PlaceReturn对象
-func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
+func Place(key config.Key, params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 	mapParams := make(map[string]string)
 	mapParams["account-id"] = params.AccountID
 	mapParams["amount"] = params.Amount
@@ -205,7 +205,7 @@ func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 
 	strRequest := "/v1/order/orders/place"
 
-	jsonPlaceReturn := untils.ApiKeyPost(mapParams, strRequest)
+	jsonPlaceReturn := untils.ApiKeyPost(key, mapParams, strRequest)
 	err = json.Unmarshal([]byte(jsonPlaceReturn), &r)
 
 	return
@@ -214,27 +214,27 @@ func Place(params models.PlaceRequestParams) (r models.PlaceReturn, err error) {
 // 申请撤销一个订单请求
 // strOrderID: 订单ID
 // return: PlaceReturn对象
-func SubmitCancel(strOrderID string) (r models.PlaceReturn, err error) {
+func SubmitCancel(key config.Key, strOrderID string) (r models.PlaceReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/order/orders/%s/submitcancel", strOrderID)
 
-	jsonPlaceReturn := untils.ApiKeyPost(make(map[string]string), strRequest)
+	jsonPlaceReturn := untils.ApiKeyPost(key, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonPlaceReturn), &r)
 
 	return
 }
 
 // 根据订单ID查询订单详情
-func GetOrderDetail(strOrderID string) (r models.OrderDetailReturn, err error) {
+func GetOrderDetail(key config.Key, strOrderID string) (r models.OrderDetailReturn, err error) {
 	strRequest := fmt.Sprintf("/v1/order/orders/%s", strOrderID)
 
-	jsonOrderReturn := untils.ApiKeyGet(make(map[string]string), strRequest)
+	jsonOrderReturn := untils.ApiKeyGet(key, make(map[string]string), strRequest)
 	err = json.Unmarshal([]byte(jsonOrderReturn), &r)
 
 	return
 }
 
 // 列出当前所有挂单
-func GetOrders(strSymbol string) (r models.OrdersReturn, err error) {
+func GetOrders(key config.Key, strSymbol string) (r models.OrdersReturn, err error) {
 	//pre-submitted 准备提交, submitted 已提交, partial-filled 部分成交, partial-canceled 部分成交撤销, filled 完全成交, canceled 已撤销
 	mapParams := make(map[string]string)
 	mapParams["symbol"] = strSymbol
@@ -242,7 +242,7 @@ func GetOrders(strSymbol string) (r models.OrdersReturn, err error) {
 
 	strRequest := "/v1/order/orders"
 
-	jsonOrdersReturn := untils.ApiKeyGet(mapParams, strRequest)
+	jsonOrdersReturn := untils.ApiKeyGet(key, mapParams, strRequest)
 	err = json.Unmarshal([]byte(jsonOrdersReturn), &r)
 
 	return
@@ -7,33 +7,44 @@ import (
 )
 
 type configure struct {
-	ACCESS_KEY string
-	SECRET_KEY string
-	dataURL    string
-	tradeURL   string
+	dataURL  string
+	tradeURL string
 }
 
 // Config 中币接口配置信息
 var (
-	Config                  configure
-	dataClient, tradeClient httpClient
+	Config     configure
+	dataClient httpClient
 )
 
+// Client 中币的交易接口,每个实例使用自己的密钥
+type Client struct {
+	accessKey string
+	secretKey string
+	trade     httpClient
+}
+
+// New 创建使用指定密钥的交易接口
+func New(accessKey, secretKey string) *Client {
+	c := &Client{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		trade:     httpClient{resty.New().SetDebug(false).SetHostURL(Config.tradeURL)},
+	}
+	c.trade.handleQueryParams(accessKey)
+	return c
+}
+
 func init() {
 
 	os.Setenv("HTTP_PROXY", "http://127.0.0.1:6667")
 	os.Setenv("HTTPS_PROXY", "https://127.0.0.1:6667")
 
-	Config.ACCESS_KEY = ""
-	Config.SECRET_KEY = ""
 	Config.dataURL = "http://api.zb.com/data/v1/"
 	Config.tradeURL = "https://trade.zb.com/api/"
 
 	c1 := resty.New().SetDebug(false).SetHostURL(Config.dataURL)
-	c2 := resty.New().SetDebug(false).SetHostURL(Config.tradeURL)
 	dataClient = httpClient{c1}
-	tradeClient = httpClient{c2}
 
-	dataClient.handleQueryParams()
-	tradeClient.handleQueryParams()
+	dataClient.handleQueryParams("")
 }
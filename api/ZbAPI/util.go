@@ -15,15 +15,15 @@ import (
 )
 
 // SHA1 加密
-func digest() string {
+func (c *Client) digest() string {
 	hash := sha1.New()
-	hash.Write([]byte(Config.SECRET_KEY))
+	hash.Write([]byte(c.secretKey))
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
 // hmac MD5
-func hmacSign(message string) string {
-	hmac := hmac.New(md5.New, []byte(digest()))
+func (c *Client) hmacSign(message string) string {
+	hmac := hmac.New(md5.New, []byte(c.digest()))
 	hmac.Write([]byte(message))
 	return hex.EncodeToString(hmac.Sum(nil))
 }
@@ -51,7 +51,7 @@ type httpClient struct {
 	*resty.Client
 }
 
-func (client *httpClient) handleQueryParams() {
+func (client *httpClient) handleQueryParams(accessKey string) {
 	client.OnAfterResponse(func(client *resty.Client, req *resty.Response) error {
 		for k := range client.QueryParam {
 			delete(client.QueryParam, k)
@@ -61,7 +61,7 @@ func (client *httpClient) handleQueryParams() {
 
 	client.OnBeforeRequest(func(client *resty.Client, req *resty.Request) error {
 		client.SetQueryParams(map[string]string{
-			"accesskey": Config.ACCESS_KEY,
+			"accesskey": accessKey,
 			"reqTime":   strconv.FormatInt(time.Now().UnixNano()/1000000, 10),
 		})
 		return nil
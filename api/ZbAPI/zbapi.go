@@ -73,8 +73,8 @@ func trades(api, market string) *respTrades {
 }
 
 // 获取用户信息
-func accountInfo(api, sign string) (*respAccountInfo, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) accountInfo(api, sign string) (*respAccountInfo, error) {
+	resp, err := c.trade.SetQueryParams(map[string]string{
 		"method": api,
 		"sign":   sign,
 	}).R().Get(api)
@@ -93,19 +93,19 @@ func accountInfo(api, sign string) (*respAccountInfo, error) {
 	return &res, err
 }
 
-func GetAccountInfo() (*respAccountInfo, error) {
+func (c *Client) GetAccountInfo() (*respAccountInfo, error) {
 	params := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
+		"accesskey": c.accessKey,
 		"method":    "getAccountInfo",
 	}
 	sorted := sortParams(params)
-	sign := hmacSign(sorted)
-	return accountInfo("getAccountInfo", sign)
+	sign := c.hmacSign(sorted)
+	return c.accountInfo("getAccountInfo", sign)
 }
 
 // 委托下单
-func createOrder(api, amount, currency, tradeType, price, sign string) (*respOrder, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) createOrder(api, amount, currency, tradeType, price, sign string) (*respOrder, error) {
+	resp, err := c.trade.SetQueryParams(map[string]string{
 		"amount":    amount,
 		"currency":  currency,
 		"method":    api,
@@ -121,9 +121,9 @@ func createOrder(api, amount, currency, tradeType, price, sign string) (*respOrd
 	return &res, err
 }
 
-func CreateOrder(amount, currency, tradeType, price string) (*respOrder, error) {
+func (c *Client) CreateOrder(amount, currency, tradeType, price string) (*respOrder, error) {
 	createOrderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
+		"accesskey": c.accessKey,
 		"amount":    amount,
 		"currency":  currency,
 		"price":     price,
@@ -131,13 +131,13 @@ func CreateOrder(amount, currency, tradeType, price string) (*respOrder, error)
 		"method":    "order",
 	}
 	createOrderSorted := sortParams(createOrderParams)
-	createOrderSign := hmacSign(createOrderSorted)
-	return createOrder("order", amount, currency, tradeType, price, createOrderSign)
+	createOrderSign := c.hmacSign(createOrderSorted)
+	return c.createOrder("order", amount, currency, tradeType, price, createOrderSign)
 }
 
 // 获取委托买单和卖单
-func getOrders(api, currency, sign string) (*respOrders, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) getOrders(api, currency, sign string) (*respOrders, error) {
+	resp, err := c.trade.SetQueryParams(map[string]string{
 		"currency":  currency,
 		"method":    api,
 		"pageIndex": "1",
@@ -162,22 +162,22 @@ func getOrders(api, currency, sign string) (*respOrders, error) {
 	return &res, err
 }
 
-func GetOrders(currency string) (*respOrders, error) {
+func (c *Client) GetOrders(currency string) (*respOrders, error) {
 	orderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
+		"accesskey": c.accessKey,
 		"currency":  currency,
 		"method":    "getUnfinishedOrdersIgnoreTradeType",
 		"pageIndex": "1",
 		"pageSize":  "10",
 	}
 	orderSorted := sortParams(orderParams)
-	orderSign := hmacSign(orderSorted)
-	return getOrders("getUnfinishedOrdersIgnoreTradeType", currency, orderSign)
+	orderSign := c.hmacSign(orderSorted)
+	return c.getOrders("getUnfinishedOrdersIgnoreTradeType", currency, orderSign)
 }
 
 // 取消委托
-func cancelOrder(api, id, currency, sign string) (*respSimple, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) cancelOrder(api, id, currency, sign string) (*respSimple, error) {
+	resp, err := c.trade.SetQueryParams(map[string]string{
 		"currency": currency,
 		"method":   api,
 		"id":       id,
@@ -191,21 +191,21 @@ func cancelOrder(api, id, currency, sign string) (*respSimple, error) {
 	return &res, err
 }
 
-func CancelOrder(id, currency string) (*respSimple, error) {
+func (c *Client) CancelOrder(id, currency string) (*respSimple, error) {
 	cancelParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
+		"accesskey": c.accessKey,
 		"currency":  currency,
 		"id":        id,
 		"method":    "cancelOrder",
 	}
 	cancelSorted := sortParams(cancelParams)
-	cancelSign := hmacSign(cancelSorted)
-	return cancelOrder("cancelOrder", id, currency, cancelSign)
+	cancelSign := c.hmacSign(cancelSorted)
+	return c.cancelOrder("cancelOrder", id, currency, cancelSign)
 }
 
 // 获取委托订单
-func getOrder(api, id, currency, sign string) (*order, error) {
-	resp, err := tradeClient.SetQueryParams(map[string]string{
+func (c *Client) getOrder(api, id, currency, sign string) (*order, error) {
+	resp, err := c.trade.SetQueryParams(map[string]string{
 		"currency": currency,
 		"method":   api,
 		"id":       id,
@@ -219,14 +219,14 @@ func getOrder(api, id, currency, sign string) (*order, error) {
 	return &res, err
 }
 
-func GetOrder(id, currency string) (*order, error) {
+func (c *Client) GetOrder(id, currency string) (*order, error) {
 	orderParams := map[string]string{
-		"accesskey": Config.ACCESS_KEY,
+		"accesskey": c.accessKey,
 		"currency":  currency,
 		"id":        id,
 		"method":    "getOrder",
 	}
 	orderSorted := sortParams(orderParams)
-	orderSign := hmacSign(orderSorted)
-	return getOrder("getOrder", id, currency, orderSign)
+	orderSign := c.hmacSign(orderSorted)
+	return c.getOrder("getOrder", id, currency, orderSign)
 }
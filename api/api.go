@@ -1,5 +1,9 @@
 package api
 
+import (
+	"github.com/phonegapX/QuantBot/model"
+)
+
 // Option is an exchange option
 type Option struct {
 	TraderID  int64
@@ -7,6 +11,7 @@ type Option struct {
 	Name      string
 	AccessKey string
 	SecretKey string
+	LogHook   func(model.Log) //交易所的每条日志写入之前调用,管理台手动交易时用来获取错误信息
 }
 
 // Exchange interface
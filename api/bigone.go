@@ -21,19 +21,18 @@ type BigOne struct {
 	records          map[string][]Record
 	logger           model.Logger
 	option           Option
+	client           *BigoneAPI.Bigone
 
 	limit     float64
 	lastSleep int64
 	lastTimes int64
 }
 
-var bo *BigoneAPI.Bigone
-
 // NewBigOne create an exchange struct of big.one
 func NewBigOne(opt Option) Exchange {
-	bo = BigoneAPI.New(http.DefaultClient, opt.AccessKey, opt.SecretKey)
 	//...
 	return &BigOne{
+		client: BigoneAPI.New(http.DefaultClient, opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
 			"BTC/USDT": "BTC-USDT",
 			"ONE/USDT": "ONE-USDT",
@@ -64,7 +63,7 @@ func NewBigOne(opt Option) Exchange {
 			"EOS/ETH":  0.001,
 		},
 		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -111,7 +110,7 @@ func (e *BigOne) GetMinAmount(stock string) float64 {
 
 // GetAccount get the account detail of this exchange
 func (e *BigOne) GetAccount() interface{} {
-	result, err := bo.GetAccount()
+	result, err := e.client.GetAccount()
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
 		return false
@@ -157,7 +156,7 @@ func (e *BigOne) Trade(tradeType string, stockType string, _price, _amount inter
 }
 
 func (e *BigOne) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := bo.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
+	result, err := e.client.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
 		return false
@@ -172,7 +171,7 @@ func (e *BigOne) buy(stockType string, price, amount float64, msgs ...interface{
 }
 
 func (e *BigOne) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := bo.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
+	result, err := e.client.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
 		return false
@@ -198,7 +197,7 @@ func (e *BigOne) GetOrders(stockType string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := bo.GetUnfinishOrders(e.stockTypeMap[stockType])
+	result, err := e.client.GetUnfinishOrders(e.stockTypeMap[stockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
 		return false
@@ -230,7 +229,7 @@ func (e *BigOne) GetTrades(stockType string) interface{} {
 
 // CancelOrder cancel an order
 func (e *BigOne) CancelOrder(order Order) bool {
-	result, err := bo.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
+	result, err := e.client.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
 		return false
@@ -251,7 +250,7 @@ func (e *BigOne) getTicker(stockType string, sizes ...interface{}) (ticker Ticke
 		err = fmt.Errorf("GetTicker() error, unrecognized stockType: %+v", stockType)
 		return
 	}
-	result, err := bo.GetDepth(e.stockTypeMap[stockType])
+	result, err := e.client.GetDepth(e.stockTypeMap[stockType])
 	if err != nil {
 		err = fmt.Errorf("GetTicker() error, %+v", err)
 		return
@@ -20,6 +20,7 @@ type Binance struct {
 	records          map[string][]Record
 	logger           model.Logger
 	option           Option
+	client           *BinanceAPI.Binance
 
 	limit     float64
 	lastSleep int64
@@ -28,9 +29,8 @@ type Binance struct {
 
 // NewBinance create an exchange struct of Binance.com
 func NewBinance(opt Option) Exchange {
-	BinanceAPI.ACCESS_KEY = opt.AccessKey
-	BinanceAPI.SECRET_KEY = opt.SecretKey
 	return &Binance{
+		client: BinanceAPI.New(opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
 			"BTC/USDT":  "BTC",
 			"ETH/USDT":  "ETH",
@@ -59,7 +59,7 @@ func NewBinance(opt Option) Exchange {
 			"QTUM/USDT": 0.001,
 		},
 		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -106,7 +106,7 @@ func (e *Binance) GetMinAmount(stock string) float64 {
 
 // GetAccount get the account detail of this exchange
 func (e *Binance) GetAccount() interface{} {
-	accountsMap, err := BinanceAPI.GetAccount()
+	accountsMap, err := e.client.GetAccount()
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
 		return false
@@ -127,6 +127,24 @@ func (e *Binance) GetAccount() interface{} {
 	return result
 }
 
+// GetPermissions get the trade and withdraw permissions of the API key
+func (e *Binance) GetPermissions() (permissions Permissions, err error) {
+	accountsMap, err := e.client.GetAccount()
+	if err != nil {
+		return
+	}
+	if _, ok := accountsMap["code"]; ok { //存在错误码
+		return permissions, fmt.Errorf("%v", accountsMap["msg"])
+	}
+	if trade, ok := accountsMap["canTrade"].(bool); ok {
+		permissions.Trade = &trade
+	}
+	if withdraw, ok := accountsMap["canWithdraw"].(bool); ok {
+		permissions.Withdraw = &withdraw
+	}
+	return
+}
+
 // Trade place an order
 func (e *Binance) Trade(tradeType string, stockType string, _price, _amount interface{}, msgs ...interface{}) interface{} {
 	stockType = strings.ToUpper(stockType)
@@ -149,7 +167,7 @@ func (e *Binance) Trade(tradeType string, stockType string, _price, _amount inte
 }
 
 func (e *Binance) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := BinanceAPI.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
+	result, err := e.client.LimitBuy(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
 		return false
@@ -164,7 +182,7 @@ func (e *Binance) buy(stockType string, price, amount float64, msgs ...interface
 }
 
 func (e *Binance) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := BinanceAPI.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
+	result, err := e.client.LimitSell(conver.StringMust(amount), conver.StringMust(price), e.stockTypeMap[stockType]+"USDT")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
 		return false
@@ -185,7 +203,7 @@ func (e *Binance) GetOrder(stockType, id string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := BinanceAPI.GetOneOrder(id, e.stockTypeMap[stockType]+"USDT")
+	result, err := e.client.GetOneOrder(id, e.stockTypeMap[stockType]+"USDT")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
 		return false
@@ -211,7 +229,7 @@ func (e *Binance) GetOrders(stockType string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := BinanceAPI.GetUnfinishOrders(e.stockTypeMap[stockType] + "USDT")
+	result, err := e.client.GetUnfinishOrders(e.stockTypeMap[stockType] + "USDT")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
 		return false
@@ -238,7 +256,7 @@ func (e *Binance) GetTrades(stockType string) interface{} {
 
 // CancelOrder cancel an order
 func (e *Binance) CancelOrder(order Order) bool {
-	ok, err := BinanceAPI.CancelOrder(order.ID, e.stockTypeMap[order.StockType]+"USDT")
+	ok, err := e.client.CancelOrder(order.ID, e.stockTypeMap[order.StockType]+"USDT")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
 		return false
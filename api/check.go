@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+//检查服务器时间时访问的交易所地址
+var serverHosts = map[string]string{
+	constant.Zb:         "https://trade.zb.com",
+	constant.Okex:       "https://www.okex.com",
+	constant.Huobi:      "https://api.huobi.pro",
+	constant.Binance:    "https://api.binance.com",
+	constant.GateIo:     "https://data.gateio.io",
+	constant.Poloniex:   "https://poloniex.com",
+	constant.OkexFuture: "https://www.okex.com",
+	constant.BigOne:     "https://big.one",
+}
+
+// Permissions is the permissions of an API key, nil means the exchange does not expose it
+type Permissions struct {
+	Trade    *bool
+	Withdraw *bool
+}
+
+// PermissionsGetter is implemented by the exchanges which expose the permissions of the API key
+type PermissionsGetter interface {
+	GetPermissions() (Permissions, error)
+}
+
+// GetClockSkew get the difference between the server clock of an exchange and the local clock,
+// it is measured by the Date header of the response, so the precision is one second
+func GetClockSkew(exchangeType string) (skew time.Duration, err error) {
+	host, ok := serverHosts[exchangeType]
+	if !ok {
+		return 0, fmt.Errorf("Unsupported exchange type %v", exchangeType)
+	}
+	c := http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
+	resp, err := c.Head(host)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	end := time.Now()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid server time: %v", err)
+	}
+	//以请求的中间时刻作为本地时间
+	local := start.Add(end.Sub(start) / 2)
+	return date.Sub(local), nil
+}
@@ -61,7 +61,7 @@ func NewGateIo(opt Option) Exchange {
 		},
 		records: make(map[string][]Record),
 		host:    "https://data.gateio.io/api2/1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -23,6 +23,8 @@ type Huobi struct {
 	records          map[string][]Record
 	logger           model.Logger
 	option           Option
+	key              config.Key
+	accountID        string
 
 	limit     float64
 	lastSleep int64
@@ -31,10 +33,9 @@ type Huobi struct {
 
 // NewHuobi create an exchange struct of huobi.com
 func NewHuobi(opt Option) Exchange {
-	config.ACCESS_KEY = opt.AccessKey
-	config.SECRET_KEY = opt.SecretKey
 	//...
 	return &Huobi{
+		key: config.Key{ACCESS_KEY: opt.AccessKey, SECRET_KEY: opt.SecretKey},
 		stockTypeMap: map[string]string{
 			"BTC/USDT":  "btc",
 			"ETH/USDT":  "eth",
@@ -65,7 +66,7 @@ func NewHuobi(opt Option) Exchange {
 			"QTUM/USDT": 0.001,
 		},
 		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -112,7 +113,7 @@ func (e *Huobi) GetMinAmount(stock string) float64 {
 
 // GetAccount get the account detail of this exchange
 func (e *Huobi) GetAccount() interface{} {
-	accounts, err := services.GetAccounts()
+	accounts, err := services.GetAccounts(e.key)
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
 		return false
@@ -134,7 +135,7 @@ func (e *Huobi) GetAccount() interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", "all account locked")
 		return false
 	}
-	balance, err := services.GetAccountBalance(strconv.FormatInt(accountID, 10))
+	balance, err := services.GetAccountBalance(e.key, strconv.FormatInt(accountID, 10))
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
 		return false
@@ -154,7 +155,7 @@ func (e *Huobi) GetAccount() interface{} {
 		}
 	}
 	//...
-	config.ACCOUNT_ID = strconv.FormatInt(accountID, 10)
+	e.accountID = strconv.FormatInt(accountID, 10)
 	//...
 	return result
 }
@@ -169,6 +170,12 @@ func (e *Huobi) Trade(tradeType string, stockType string, _price, _amount interf
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Trade() error, unrecognized stockType: ", stockType)
 		return false
 	}
+	//下单需要现货账户的ID,在GetAccount()中获取
+	if e.accountID == "" {
+		if _, ok := e.GetAccount().(map[string]float64); !ok {
+			return false
+		}
+	}
 	switch tradeType {
 	case constant.TradeTypeBuy:
 		return e.buy(stockType, price, amount, msgs...)
@@ -182,14 +189,14 @@ func (e *Huobi) Trade(tradeType string, stockType string, _price, _amount interf
 
 func (e *Huobi) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
 	params := models.PlaceRequestParams{
-		AccountID: config.ACCOUNT_ID,                  // 账户ID
+		AccountID: e.accountID,                        // 账户ID
 		Amount:    conver.StringMust(amount),          // 限价表示下单数量, 市价买单时表示买多少钱, 市价卖单时表示卖多少币
 		Price:     conver.StringMust(price),           // 下单价格, 市价单不传该参数
 		Source:    "api",                              // 订单来源, api: API调用, margin-api: 借贷资产交易
 		Symbol:    e.stockTypeMap[stockType] + "usdt", // 交易对, btcusdt, bccbtc......
 		Type:      "buy-limit",                        // 订单类型, buy-market: 市价买, sell-market: 市价卖, buy-limit: 限价买, sell-limit: 限价卖
 	}
-	result, err := services.Place(params)
+	result, err := services.Place(e.key, params)
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
 		return false
@@ -204,14 +211,14 @@ func (e *Huobi) buy(stockType string, price, amount float64, msgs ...interface{}
 
 func (e *Huobi) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
 	params := models.PlaceRequestParams{
-		AccountID: config.ACCOUNT_ID,                  // 账户ID
+		AccountID: e.accountID,                        // 账户ID
 		Amount:    conver.StringMust(amount),          // 限价表示下单数量, 市价买单时表示买多少钱, 市价卖单时表示卖多少币
 		Price:     conver.StringMust(price),           // 下单价格, 市价单不传该参数
 		Source:    "api",                              // 订单来源, api: API调用, margin-api: 借贷资产交易
 		Symbol:    e.stockTypeMap[stockType] + "usdt", // 交易对, btcusdt, bccbtc......
 		Type:      "sell-limit",                       // 订单类型, buy-market: 市价买, sell-market: 市价卖, buy-limit: 限价买, sell-limit: 限价卖
 	}
-	result, err := services.Place(params)
+	result, err := services.Place(e.key, params)
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
 		return false
@@ -231,7 +238,7 @@ func (e *Huobi) GetOrder(stockType, id string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := services.GetOrderDetail(id)
+	result, err := services.GetOrderDetail(e.key, id)
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
 		return false
@@ -257,7 +264,7 @@ func (e *Huobi) GetOrders(stockType string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := services.GetOrders(e.stockTypeMap[stockType] + "usdt")
+	result, err := services.GetOrders(e.key, e.stockTypeMap[stockType]+"usdt")
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
 		return false
@@ -288,7 +295,7 @@ func (e *Huobi) GetTrades(stockType string) interface{} {
 
 // CancelOrder cancel an order
 func (e *Huobi) CancelOrder(order Order) bool {
-	result, err := services.SubmitCancel(order.ID)
+	result, err := services.SubmitCancel(e.key, order.ID)
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
 		return false
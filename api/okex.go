@@ -65,7 +65,7 @@ func NewOKEX(opt Option) Exchange {
 		},
 		records: make(map[string][]Record),
 		host:    "https://www.okex.com/api/v1/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -165,7 +165,7 @@ func NewPoloniex(opt Option) Exchange {
 		},
 		records: make(map[string][]Record),
 		host:    "https://poloniex.com/",
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
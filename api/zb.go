@@ -20,6 +20,7 @@ type Zb struct {
 	records          map[string][]Record
 	logger           model.Logger
 	option           Option
+	client           *ZbAPI.Client
 
 	limit     float64
 	lastSleep int64
@@ -28,10 +29,9 @@ type Zb struct {
 
 // NewZb create an exchange struct of zb.com
 func NewZb(opt Option) Exchange {
-	ZbAPI.Config.ACCESS_KEY = opt.AccessKey
-	ZbAPI.Config.SECRET_KEY = opt.SecretKey
 	//...
 	return &Zb{
+		client: ZbAPI.New(opt.AccessKey, opt.SecretKey),
 		stockTypeMap: map[string]string{
 			"BTC/USDT":  "btc_usdt",
 			"ETH/USDT":  "eth_usdt",
@@ -60,7 +60,7 @@ func NewZb(opt Option) Exchange {
 			"QTUM/USDT": 0.001,
 		},
 		records: make(map[string][]Record),
-		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type},
+		logger:  model.Logger{TraderID: opt.TraderID, ExchangeType: opt.Type, Hook: opt.LogHook},
 		option:  opt,
 
 		limit:     10.0,
@@ -107,7 +107,7 @@ func (e *Zb) GetMinAmount(stock string) float64 {
 
 // GetAccount get the account detail of this exchange
 func (e *Zb) GetAccount() interface{} {
-	accountInfo, err := ZbAPI.GetAccountInfo()
+	accountInfo, err := e.client.GetAccountInfo()
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetAccount() error, ", err)
 		return false
@@ -154,7 +154,7 @@ func (e *Zb) Trade(tradeType string, stockType string, _price, _amount interface
 }
 
 func (e *Zb) buy(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := ZbAPI.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "1", conver.StringMust(price))
+	result, err := e.client.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "1", conver.StringMust(price))
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Buy() error, ", err)
 		return false
@@ -168,7 +168,7 @@ func (e *Zb) buy(stockType string, price, amount float64, msgs ...interface{}) i
 }
 
 func (e *Zb) sell(stockType string, price, amount float64, msgs ...interface{}) interface{} {
-	result, err := ZbAPI.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "0", conver.StringMust(price))
+	result, err := e.client.CreateOrder(conver.StringMust(amount), e.stockTypeMap[stockType], "0", conver.StringMust(price))
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "Sell() error, ", err)
 		return false
@@ -188,7 +188,7 @@ func (e *Zb) GetOrder(stockType, id string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := ZbAPI.GetOrder(id, e.stockTypeMap[stockType])
+	result, err := e.client.GetOrder(id, e.stockTypeMap[stockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrder() error, ", err)
 		return false
@@ -214,7 +214,7 @@ func (e *Zb) GetOrders(stockType string) interface{} {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, unrecognized stockType: ", stockType)
 		return false
 	}
-	result, err := ZbAPI.GetOrders(e.stockTypeMap[stockType])
+	result, err := e.client.GetOrders(e.stockTypeMap[stockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "GetOrders() error, ", err)
 		return false
@@ -241,7 +241,7 @@ func (e *Zb) GetTrades(stockType string) interface{} {
 
 // CancelOrder cancel an order
 func (e *Zb) CancelOrder(order Order) bool {
-	result, err := ZbAPI.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
+	result, err := e.client.CancelOrder(order.ID, e.stockTypeMap[order.StockType])
 	if err != nil {
 		e.logger.Log(constant.ERROR, "", 0.0, 0.0, "CancelOrder() error, ", err)
 		return false
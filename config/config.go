@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/go-ini/ini"
@@ -30,3 +31,27 @@ func init() {
 func String(key string) string {
 	return confs[strings.ToLower(key)]
 }
+
+// Int get an integer config, the default value is returned when it is not set or invalid
+func Int(key string, value int) int {
+	if v, err := strconv.Atoi(String(key)); err == nil {
+		return v
+	}
+	return value
+}
+
+// HostAllowed check if the host is in the comma separated hosts of the config,
+// "*.example.com" matches all subdomains and an empty config allows nothing
+func HostAllowed(key, host string) bool {
+	host = strings.ToLower(host)
+	for _, h := range strings.Split(String(key), ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			continue
+		}
+		if h == host || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ const (
 	Version                    = "0.0.3"
 	ErrAuthorizationError      = "Authorization Error"
 	ErrInsufficientPermissions = "Insufficient Permissions"
+	AdminLevel                 = 99 //初始管理员的权限等级,控制台等调试功能只对管理员开放
 )
 
 // exchange types
@@ -32,6 +33,7 @@ const (
 	LONGCLOSE  = "LONG_CLOSE"
 	SHORTCLOSE = "SHORT_CLOSE"
 	CANCEL     = "CANCEL"
+	DEBUG      = "DEBUG"
 )
 
 // trade types
@@ -44,8 +46,43 @@ const (
 	TradeTypeShortClose = "SHORT_CLOSE"
 )
 
+// notify channel types
+const (
+	NotifyEmail    = "email"
+	NotifyWebhook  = "webhook"
+	NotifyTelegram = "telegram"
+	NotifyDingTalk = "dingtalk" //钉钉和企业微信的群机器人
+)
+
+// system events
+const (
+	EventTraderCrashed = "TraderCrashed" //策略出错退出或者被看门狗中断
+	EventResourceLimit = "ResourceLimit" //策略超出CPU或者对象数量限制
+	EventOrderFilled   = "OrderFilled"   //策略查询到完全成交的订单
+	EventNewLoginIP    = "NewLoginIP"    //用户从新的IP登录
+)
+
+// chart series types
+const (
+	ChartLine        = "line"
+	ChartBar         = "bar"
+	ChartScatter     = "scatter" //买卖信号等稀疏的点
+	ChartCandlestick = "candlestick"
+)
+
+// API token scopes
+const (
+	TokenScopeRead  = "read"  //只能查看
+	TokenScopeTrade = "trade" //查看、启停策略和手动交易
+	TokenScopeAdmin = "admin" //和登录的用户权限相同
+)
+
 // some variables
 var (
 	Consts        = []string{"M", "M5", "M15", "M30", "H", "D", "W"}
 	ExchangeTypes = []string{Zb, Okex, Huobi, Binance, GateIo, Poloniex, OkexFuture, BigOne}
+	NotifyTypes   = []string{NotifyEmail, NotifyWebhook, NotifyTelegram, NotifyDingTalk}
+	Events        = []string{EventTraderCrashed, EventResourceLimit, EventOrderFilled, EventNewLoginIP}
+	ChartTypes    = []string{ChartLine, ChartBar, ChartScatter, ChartCandlestick}
+	TokenScopes   = []string{TokenScopeRead, TokenScopeTrade, TokenScopeAdmin}
 )
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
+)
+
+type account struct{}
+
+// Quotes list the supported quote currencies
+func (account) Quotes(_ string, ctx rpc.Context) (resp response) {
+	resp.Data = trader.AccountQuotes
+	resp.Success = true
+	return
+}
+
+// Overview get the balances and the values of all the exchanges which the user owns
+func (account) Overview(quote string, refresh bool, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	overview, err := trader.GetAccountOverview(self, quote, refresh)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = overview
+	resp.Success = true
+	return
+}
+
+// History list the account snapshots between from and to (milliseconds) for the equity chart
+func (account) History(quote string, from, to int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	quote = strings.ToUpper(quote)
+	if err := trader.CheckAccountQuote(quote); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	snapshots, err := self.ListAccountSnapshot(quote, from, to)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = snapshots
+	resp.Success = true
+	return
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/hprose/hprose-golang/rpc"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
 )
 
 type algorithm struct{}
@@ -50,6 +51,17 @@ func (algorithm) Put(req model.Algorithm, ctx rpc.Context) (resp response) {
 		resp.Message = fmt.Sprint(err)
 		return
 	}
+	//脚本有语法错误时不保存,警告和错误一起返回给编辑器
+	if err := trader.CheckEngine(req.Engine); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	diagnostics := trader.CheckScript(req.Script, req.Engine)
+	resp.Data = diagnostics
+	if trader.HasError(diagnostics) {
+		resp.Message = "Script error"
+		return
+	}
 	algorithm := req
 	if req.ID > 0 {
 		if err := model.DB.First(&algorithm, req.ID).Error; err != nil {
@@ -59,8 +71,9 @@ func (algorithm) Put(req model.Algorithm, ctx rpc.Context) (resp response) {
 		algorithm.Name = req.Name
 		algorithm.Description = req.Description
 		algorithm.Script = req.Script
+		algorithm.Engine = req.Engine
 		algorithm.EvnDefault = req.EvnDefault
-		if err := model.DB.Save(&algorithm).Error; err != nil {
+		if _, err := self.SaveAlgorithm(&algorithm, req.Comment); err != nil {
 			resp.Message = fmt.Sprint(err)
 			return
 		}
@@ -68,7 +81,105 @@ func (algorithm) Put(req model.Algorithm, ctx rpc.Context) (resp response) {
 		return
 	}
 	req.UserID = self.ID
-	if err := model.DB.Create(&req).Error; err != nil {
+	if _, err := self.SaveAlgorithm(&req, req.Comment); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Versions
+func (algorithm) Versions(id, size, page int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, versions, err := self.ListAlgorithmVersion(id, size, page)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total int64
+		List  []model.AlgorithmVersion
+	}{
+		Total: total,
+		List:  versions,
+	}
+	resp.Success = true
+	return
+}
+
+// Diff
+func (algorithm) Diff(id, from, to int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if _, err := self.GetAlgorithm(id); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	a, err := model.GetAlgorithmVersion(id, from)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	b, err := model.GetAlgorithmVersion(id, to)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Script     []model.DiffLine
+		EvnDefault []model.DiffLine
+	}{
+		Script:     model.DiffLines(a.Script, b.Script),
+		EvnDefault: model.DiffLines(a.EvnDefault, b.EvnDefault),
+	}
+	resp.Success = true
+	return
+}
+
+// Rollback
+func (algorithm) Rollback(id, version int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	algorithm, err := self.GetAlgorithm(id)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	v, err := model.GetAlgorithmVersion(id, version)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	algorithm.Script = v.Script
+	algorithm.Engine = v.Engine
+	algorithm.EvnDefault = v.EvnDefault
+	if resp.Data, err = self.SaveAlgorithm(&algorithm, fmt.Sprintf("Rollback to version %v", version)); err != nil {
 		resp.Message = fmt.Sprint(err)
 		return
 	}
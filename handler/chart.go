@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+const (
+	chartPoints    = 500  //图表每个系列默认返回的最大点数
+	chartMaxPoints = 5000 //图表每个系列最多返回的点数
+)
+
+type chart struct{}
+
+// List list the chart layouts of a trader
+func (chart) List(trader model.Trader, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if resp.Data, err = self.ListChartLayout(trader.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Series get the downsampled series of a chart between from and to (milliseconds)
+func (chart) Series(trader model.Trader, name string, from, to int64, points int, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if points <= 0 {
+		points = chartPoints
+	} else if points > chartMaxPoints {
+		points = chartMaxPoints
+	}
+	if resp.Data, err = self.ListChartSeries(trader.ID, name, from, to, points); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
@@ -2,14 +2,90 @@ package handler
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/api"
+	"github.com/phonegapX/QuantBot/config"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
 )
 
 type exchange struct{}
 
+//exchangeTest 交易所密钥的检查结果
+type exchangeTest struct {
+	Valid       bool             //密钥是否可以访问账户
+	Error       string           //密钥无效时的错误信息
+	Permissions *api.Permissions //交易和提现权限,交易所不提供时为nil
+	ClockSkew   int64            //交易所服务器时间-本地时间,单位毫秒
+	ClockError  string           //获取服务器时间失败时的错误信息
+}
+
+//testExchange 创建交易所并获取账户,检查密钥是否可用
+func testExchange(e model.Exchange) (result exchangeTest) {
+	errors := []string{}
+	ex, err := trader.NewExchange(api.Option{
+		Type:      e.Type,
+		Name:      e.Name,
+		AccessKey: e.AccessKey,
+		SecretKey: e.SecretKey,
+		LogHook: func(l model.Log) {
+			if l.Type == constant.ERROR {
+				errors = append(errors, l.Message)
+			}
+		},
+	})
+	if err != nil {
+		result.Error = fmt.Sprint(err)
+		return
+	}
+	if skew, err := api.GetClockSkew(e.Type); err != nil {
+		result.ClockError = fmt.Sprint(err)
+	} else {
+		result.ClockSkew = skew.Nanoseconds() / 1000000
+	}
+	if _, ok := ex.GetAccount().(map[string]float64); !ok {
+		result.Error = strings.Join(errors, "; ")
+		if result.Error == "" {
+			result.Error = "GetAccount() failed"
+		}
+		return
+	}
+	result.Valid = true
+	if getter, ok := ex.(api.PermissionsGetter); ok {
+		if permissions, err := getter.GetPermissions(); err == nil {
+			result.Permissions = &permissions
+		}
+	}
+	return
+}
+
+// Test check the keys of an exchange by an authenticated read-only call,
+// the saved keys are used when the ID is set and the keys are empty
+func (exchange) Test(req model.Exchange, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if req.ID > 0 && req.AccessKey == "" && req.SecretKey == "" {
+		if req, err = self.GetExchange(req.ID); err != nil {
+			resp.Message = fmt.Sprint(err)
+			return
+		}
+	}
+	resp.Data = testExchange(req)
+	resp.Success = true
+	return
+}
+
 // Types ...
 func (exchange) Types(_ string, ctx rpc.Context) (resp response) {
 	resp.Data = constant.ExchangeTypes
@@ -34,6 +110,7 @@ func (exchange) List(size, page int64, order string, ctx rpc.Context) (resp resp
 		resp.Message = fmt.Sprint(err)
 		return
 	}
+	hideExchangeKeys(ctx, exchanges)
 	resp.Data = struct {
 		Total int64
 		List  []model.Exchange
@@ -57,6 +134,17 @@ func (exchange) Put(req model.Exchange, ctx rpc.Context) (resp response) {
 		resp.Message = fmt.Sprint(err)
 		return
 	}
+	//请求或者配置指定了保存时检查,密钥无效时不保存
+	test := config.String("exchangetestonsave") == "true"
+	if req.Test != nil {
+		test = *req.Test
+	}
+	if test {
+		if result := testExchange(req); !result.Valid {
+			resp.Message = fmt.Sprint("Exchange test failed: ", result.Error)
+			return
+		}
+	}
 	exchange := req
 	if req.ID > 0 {
 		if err := model.DB.First(&exchange, req.ID).Error; err != nil {
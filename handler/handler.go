@@ -3,14 +3,19 @@ package handler
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/hprose/hprose-golang/rpc"
 	"github.com/phonegapX/QuantBot/config"
 	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
 )
 
 type response struct {
@@ -25,6 +30,24 @@ func (e event) OnSendHeader(ctx *rpc.HTTPContext) {
 	ctx.Response.Header().Set("Access-Control-Allow-Headers", "Authorization")
 }
 
+//remoteIP 请求的来源IP
+func remoteIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+//shutdown 收到退出信号时写入队列中的日志再退出
+func shutdown() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	<-c
+	model.FlushLog()
+	log.Println("Server stopped")
+	os.Exit(0)
+}
+
 // Server ...
 func Server() {
 	port := config.String("port")
@@ -32,21 +55,33 @@ func Server() {
 	handler := struct {
 		User      user
 		Exchange  exchange
+		Account   account
 		Algorithm algorithm
+		Library   library
+		Notify    notifier
+		Chart     chart
 		Trader    runner
 		Log       logger
+		Manual    manual
+		Token     apiToken
 	}{}
 	service.Event = event{}
 	service.AddBeforeFilterHandler(func(request []byte, ctx rpc.Context, next rpc.NextFilterHandler) (response []byte, err error) {
 		ctx.SetInt64("start", time.Now().UnixNano())
 		httpContext := ctx.(*rpc.HTTPContext)
 		if httpContext != nil {
-			ctx.SetString("username", parseToken(httpContext.Request.Header.Get("Authorization")))
+			username, scope := authorize(httpContext.Request.Header.Get("Authorization"))
+			ctx.SetString("username", username)
+			ctx.SetString("scope", scope)
+			ctx.SetString("ip", remoteIP(httpContext.Request))
 		}
 		return next(request, ctx)
 	})
 	service.AddInvokeHandler(func(name string, args []reflect.Value, ctx rpc.Context, next rpc.NextInvokeHandler) (results []reflect.Value, err error) {
 		name = strings.Replace(name, "_", ".", 1)
+		if !scopeAllows(ctx.GetString("scope"), name) {
+			return []reflect.Value{reflect.ValueOf(response{Message: constant.ErrInsufficientPermissions})}, nil
+		}
 		results, err = next(name, args, ctx)
 		spend := (time.Now().UnixNano() - ctx.GetInt64("start")) / 1000000
 		spendInfo := ""
@@ -60,8 +95,11 @@ func Server() {
 	})
 	service.AddAllMethods(handler)
 	http.Handle("/api", service)
+	http.HandleFunc("/stream", stream)
+	http.HandleFunc(restPrefix+"/", rest)
 	http.Handle("/", http.FileServer(http.Dir("web/dist")))
 	fmt.Printf("%v  Version %v\n", constant.Banner, constant.Version)
 	log.Printf("Running at http://localhost:%v\n", port)
+	go shutdown()
 	http.ListenAndServe(":"+port, nil)
 }
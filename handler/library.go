@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
+)
+
+//模块库的名称,require时使用"name@version"指定版本
+var libraryName = regexp.MustCompile(`^[A-Za-z_][\w\-.]*$`)
+
+type library struct{}
+
+// List ...
+func (library) List(size, page int64, order string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, libraries, err := self.ListLibrary(size, page, order)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total int64
+		List  []model.Library
+	}{
+		Total: total,
+		List:  libraries,
+	}
+	resp.Success = true
+	return
+}
+
+// Put
+func (library) Put(req model.Library, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if !libraryName.MatchString(req.Name) {
+		resp.Message = "Invalid library name"
+		return
+	}
+	diagnostics := trader.CheckLibrary(req.Script)
+	resp.Data = diagnostics
+	if trader.HasError(diagnostics) {
+		resp.Message = "Script error"
+		return
+	}
+	if _, err := self.SaveLibrary(req); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Delete
+func (library) Delete(ids []int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	userIds := []int64{}
+	_, users, err := self.ListUser(-1, 1, "id")
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	for _, u := range users {
+		userIds = append(userIds, u.ID)
+	}
+	if err := model.DB.Where("id in (?) AND user_id in (?)", ids, userIds).Delete(&model.Library{}).Error; err != nil {
+		resp.Message = fmt.Sprint(err)
+	} else {
+		resp.Success = true
+	}
+	return
+}
@@ -2,12 +2,17 @@ package handler
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/hprose/hprose-golang/rpc"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
 )
 
+//收益曲线默认返回的最大点数
+const profitPoints = 100
+
 type logger struct{}
 
 type pagination struct {
@@ -19,8 +24,15 @@ type pagination struct {
 type filters struct {
 	Type         []string
 	ExchangeType []string
+	StockType    []string
+	From         int64  //毫秒时间戳
+	To           int64  //毫秒时间戳
+	Message      string //消息中包含的关键字
+	Cursor       string //上一页返回的游标,用于深度翻页
 }
 
+// List list the logs of a trader which match the filters, when filters.Cursor is set
+// the logs after the cursor are returned and the total is not counted
 func (logger) List(trader model.Trader, pagination pagination, filters filters, ctx rpc.Context) (resp response) {
 	username := ctx.GetString("username")
 	if username == "" {
@@ -36,7 +48,41 @@ func (logger) List(trader model.Trader, pagination pagination, filters filters,
 		resp.Message = fmt.Sprint(err)
 		return
 	}
-	total, logs, err := self.ListLog(trader.ID, pagination.PageSize, pagination.Current)
+	total, logs, cursor, err := self.ListLog(trader.ID, model.LogFilter(filters), pagination.PageSize, pagination.Current)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total  int64
+		List   []model.Log
+		Cursor string
+	}{
+		Total:  total,
+		List:   logs,
+		Cursor: cursor,
+	}
+	resp.Success = true
+	return
+}
+
+// Debug list the Console output of a trader
+func (logger) Debug(trader model.Trader, pagination pagination, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if trader, err = self.GetTrader(trader.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, logs, err := self.ListDebugLog(trader.ID, pagination.PageSize, pagination.Current)
 	if err != nil {
 		resp.Message = fmt.Sprint(err)
 		return
@@ -52,6 +98,92 @@ func (logger) List(trader model.Trader, pagination pagination, filters filters,
 	return
 }
 
+// Profits get the downsampled profit curve and the performance statistics of a trader between from and to (milliseconds)
+func (logger) Profits(trader model.Trader, from, to int64, points int, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if trader, err = self.GetTrader(trader.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if points <= 0 {
+		points = profitPoints
+	}
+	if resp.Data, err = self.ListProfitStats(trader.ID, from, to, points); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Clear delete the logs of a trader before the timestamp (milliseconds),
+// 0 means before the last run of the trader
+func (logger) Clear(trader model.Trader, before int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if trader, err = self.GetTrader(trader.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	timestamp := before * int64(time.Millisecond)
+	if before <= 0 {
+		if trader.LastRunAt.IsZero() {
+			resp.Message = "The trader has never run"
+			return
+		}
+		timestamp = trader.LastRunAt.UnixNano()
+	}
+	count, err := self.ClearLog(trader.ID, timestamp)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := self.CreateAudit(trader.ID, "ClearLog", time.Unix(0, timestamp).String(), fmt.Sprint(count, " logs deleted")); err != nil {
+		log.Println("Create audit error:", err)
+	}
+	resp.Data = count
+	resp.Success = true
+	return
+}
+
+// Writer get the counters of the log writer
+func (logger) Writer(ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if self.Level < constant.AdminLevel {
+		resp.Message = constant.ErrInsufficientPermissions
+		return
+	}
+	resp.Data = model.GetLogWriterStats()
+	resp.Success = true
+	return
+}
+
 // // Post /logs
 // func logs(c *iris.Context) {
 // 	resp := iris.Map{
@@ -115,99 +247,6 @@ func (logger) List(trader model.Trader, pagination pagination, filters filters,
 // 	c.JSON(iris.StatusOK, resp)
 // }
 
-// // Delete /logs
-// func logsDelete(c *iris.Context) {
-// 	resp := iris.Map{
-// 		"success": false,
-// 		"msg":     "",
-// 	}
-// 	self, err := model.GetUser(jwtmid.Get(c).Claims.(jwt.MapClaims)["sub"])
-// 	if err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	td := model.Trader{}
-// 	if td, err = model.GetTrader(self, c.URLParam("id")); err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	timeType := c.URLParam("type")
-// 	minTimestamp := int64(0)
-// 	switch timeType {
-// 	case constant.LastTime:
-// 		if t := trader.Executor[td.ID]; t != nil {
-// 			minTimestamp = t.LastRunAt.Unix()
-// 		} else {
-// 			resp["msg"] = "Not found running trader"
-// 			c.JSON(iris.StatusOK, resp)
-// 			return
-// 		}
-// 	case constant.Day:
-// 		minTimestamp = time.Now().AddDate(0, 0, -1).Unix()
-// 	case constant.Week:
-// 		minTimestamp = time.Now().AddDate(0, 0, -7).Unix()
-// 	case constant.Month:
-// 		minTimestamp = time.Now().AddDate(0, -1, 0).Unix()
-// 	}
-// 	if err := model.DB.Where("trader_id = ?", td.ID).Where("timestamp < ?", minTimestamp).Delete(&model.Log{}).Error; err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	resp["success"] = true
-// 	c.JSON(iris.StatusOK, resp)
-// }
-
-// // Get /profits
-// func profits(c *iris.Context) {
-// 	resp := iris.Map{
-// 		"success": false,
-// 		"msg":     "",
-// 	}
-// 	self, err := model.GetUser(jwtmid.Get(c).Claims.(jwt.MapClaims)["sub"])
-// 	if err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	td := model.Trader{}
-// 	if td, err = model.GetTrader(self, c.URLParam("id")); err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	logs := []model.Log{}
-// 	if err := model.DB.Where("trader_id = ?", td.ID).Where("type = 1").Order("timestamp").Order("id").Find(&logs).Error; err != nil {
-// 		resp["msg"] = fmt.Sprint(err)
-// 		c.JSON(iris.StatusOK, resp)
-// 		return
-// 	}
-// 	amount, _ := c.URLParamInt("amount")
-// 	if amount == 0 {
-// 		amount = 100
-// 	}
-// 	if len(logs) < amount {
-// 		amount = len(logs)
-// 	}
-// 	data := []model.Log{}
-// 	loc, err := time.LoadLocation(config.String("logstimezone"))
-// 	if err != nil || loc == nil {
-// 		loc = time.Local
-// 	}
-// 	for i := 1; i <= amount; i++ {
-// 		index := i*len(logs)/amount - 1
-// 		data = append(data, model.Log{
-// 			Time:   time.Unix(logs[index].Timestamp, 0).In(loc).Format("01/02 15:04:05"),
-// 			Amount: logs[index].Amount,
-// 		})
-// 	}
-// 	resp["data"] = data
-// 	resp["success"] = true
-// 	c.JSON(iris.StatusOK, resp)
-// }
-
 // // Get /status
 // func status(c *iris.Context) {
 // 	resp := iris.Map{
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/api"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
+)
+
+//管理台通过保存的交易所配置手动交易,例如平仓或者撤销卡住的订单
+type manual struct{}
+
+//manualSession 一次手动操作使用的交易所,收集调用过程中的错误日志
+type manualSession struct {
+	self     model.User
+	exchange model.Exchange
+	api      api.Exchange
+	mutex    sync.Mutex
+	errors   []string
+}
+
+func newManualSession(self model.User, exchangeID int64) (s *manualSession, err error) {
+	s = &manualSession{self: self}
+	if s.exchange, err = self.GetExchange(exchangeID); err != nil {
+		return
+	}
+	s.api, err = trader.NewExchange(api.Option{
+		Type:      s.exchange.Type,
+		Name:      s.exchange.Name,
+		AccessKey: s.exchange.AccessKey,
+		SecretKey: s.exchange.SecretKey,
+		LogHook:   s.hook,
+	})
+	return
+}
+
+func (s *manualSession) hook(l model.Log) {
+	if l.Type != constant.ERROR {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.errors = append(s.errors, l.Message)
+}
+
+//result 交易所的方法失败时返回false,转换为收集到的错误信息
+func (s *manualSession) result(method string, result interface{}) (interface{}, error) {
+	if ok, isBool := result.(bool); !isBool || ok {
+		return result, nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.errors) > 0 {
+		return nil, fmt.Errorf("%v", strings.Join(s.errors, "; "))
+	}
+	return nil, fmt.Errorf("%v() failed", method)
+}
+
+//audit 记录手动操作的参数和结果
+func (s *manualSession) audit(action string, detail interface{}, result interface{}, err error) {
+	bs, _ := json.Marshal(struct {
+		Exchange string
+		Request  interface{}
+	}{
+		Exchange: fmt.Sprintf("%v(%v)", s.exchange.Name, s.exchange.ID),
+		Request:  detail,
+	})
+	audit := ""
+	if err != nil {
+		audit = fmt.Sprint("Error: ", err)
+	} else if rs, e := json.Marshal(result); e == nil {
+		audit = string(rs)
+	} else {
+		audit = fmt.Sprint(result)
+	}
+	if err := s.self.CreateExchangeAudit(s.exchange.ID, action, string(bs), audit); err != nil {
+		log.Println("Create audit error:", err)
+	}
+}
+
+//call 执行一次手动操作并写入审计记录
+func (manual) call(action string, exchangeID int64, detail interface{}, fn func(s *manualSession) (interface{}, error), ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	s, err := newManualSession(self, exchangeID)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	result, err := fn(s)
+	s.audit(action, detail, result, err)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = result
+	resp.Success = true
+	return
+}
+
+// Account get the account of an exchange
+func (m manual) Account(exchangeID int64, ctx rpc.Context) (resp response) {
+	return m.call("ManualAccount", exchangeID, nil, func(s *manualSession) (interface{}, error) {
+		return s.result("GetAccount", s.api.GetAccount())
+	}, ctx)
+}
+
+// Ticker get the ticker of a stock on an exchange
+func (m manual) Ticker(exchangeID int64, stockType string, ctx rpc.Context) (resp response) {
+	return m.call("ManualTicker", exchangeID, stockType, func(s *manualSession) (interface{}, error) {
+		return s.result("GetTicker", s.api.GetTicker(stockType))
+	}, ctx)
+}
+
+// Orders list the unfilled orders of a stock on an exchange
+func (m manual) Orders(exchangeID int64, stockType string, ctx rpc.Context) (resp response) {
+	return m.call("ManualOrders", exchangeID, stockType, func(s *manualSession) (interface{}, error) {
+		return s.result("GetOrders", s.api.GetOrders(stockType))
+	}, ctx)
+}
+
+// Trade place an order on an exchange, the price <= 0 means a market order
+func (m manual) Trade(exchangeID int64, tradeType, stockType string, price, amount float64, ctx rpc.Context) (resp response) {
+	detail := map[string]interface{}{
+		"TradeType": tradeType,
+		"StockType": stockType,
+		"Price":     price,
+		"Amount":    amount,
+	}
+	return m.call("ManualTrade", exchangeID, detail, func(s *manualSession) (interface{}, error) {
+		return s.result("Trade", s.api.Trade(tradeType, stockType, price, amount, "Manual trade by ", s.self.Username))
+	}, ctx)
+}
+
+// Cancel cancel an order on an exchange
+func (m manual) Cancel(exchangeID int64, stockType, orderID string, ctx rpc.Context) (resp response) {
+	detail := map[string]interface{}{
+		"StockType": stockType,
+		"OrderID":   orderID,
+	}
+	return m.call("ManualCancel", exchangeID, detail, func(s *manualSession) (interface{}, error) {
+		result, err := s.result("GetOrder", s.api.GetOrder(stockType, orderID))
+		if err != nil {
+			return nil, err
+		}
+		order, ok := result.(api.Order)
+		if !ok {
+			return nil, fmt.Errorf("Invalid order %v", orderID)
+		}
+		return s.result("CancelOrder", s.api.CancelOrder(order))
+	}, ctx)
+}
+
+// Audits list the audits of the manual operations on an exchange
+func (manual) Audits(exchangeID, size, page int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, audits, err := self.ListExchangeAudit(exchangeID, size, page)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total int64
+		List  []model.Audit
+	}{
+		Total: total,
+		List:  audits,
+	}
+	resp.Success = true
+	return
+}
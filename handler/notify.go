@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/notify"
+)
+
+type notifier struct{}
+
+// Types ...
+func (notifier) Types(_ string, ctx rpc.Context) (resp response) {
+	resp.Data = struct {
+		Types  []string
+		Events []string
+	}{
+		Types:  constant.NotifyTypes,
+		Events: constant.Events,
+	}
+	resp.Success = true
+	return
+}
+
+// List ...
+func (notifier) List(size, page int64, order string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, channels, err := self.ListNotifyChannel(size, page, order)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total int64
+		List  []model.NotifyChannel
+	}{
+		Total: total,
+		List:  channels,
+	}
+	resp.Success = true
+	return
+}
+
+//checkEvents 订阅的事件需要是系统支持的事件
+func checkEvents(events string) error {
+	for _, e := range strings.Split(events, ",") {
+		if e = strings.TrimSpace(e); e == "" || e == "*" {
+			continue
+		}
+		found := false
+		for _, event := range constant.Events {
+			if e == event {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown event %v", e)
+		}
+	}
+	return nil
+}
+
+// Put
+func (notifier) Put(req model.NotifyChannel, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if req.Name == "" {
+		resp.Message = "Name can not be empty"
+		return
+	}
+	if err := notify.Check(req.Type, req.Config); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := checkEvents(req.Events); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if c, err := self.GetNotifyChannel(req.Name); err == nil && c.ID != req.ID {
+		resp.Message = fmt.Sprintf("The notify channel %v already exists", req.Name)
+		return
+	}
+	channel := req
+	if req.ID > 0 {
+		if channel, err = self.GetNotifyChannel(req.ID); err != nil {
+			resp.Message = fmt.Sprint(err)
+			return
+		}
+		channel.Name = req.Name
+		channel.Type = req.Type
+		channel.Config = req.Config
+		channel.Events = req.Events
+		channel.Enabled = req.Enabled
+		if err := model.DB.Save(&channel).Error; err != nil {
+			resp.Message = fmt.Sprint(err)
+			return
+		}
+		resp.Success = true
+		return
+	}
+	req.UserID = self.ID
+	if err := model.DB.Create(&req).Error; err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Test
+func (notifier) Test(id int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	channel, err := self.GetNotifyChannel(id)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := notify.Test(channel); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
+
+// Delete
+func (notifier) Delete(ids []int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := model.DB.Where("id in (?) AND user_id = ?", ids, self.ID).Delete(&model.NotifyChannel{}).Error; err != nil {
+		resp.Message = fmt.Sprint(err)
+	} else {
+		resp.Success = true
+	}
+	return
+}
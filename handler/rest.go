@@ -0,0 +1,576 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+//REST接口的路由前缀,请求体和响应都是JSON
+const restPrefix = "/v1"
+
+//restParam 查询参数,Type为OpenAPI的类型,array类型用逗号分隔多个值
+type restParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+//restRoute REST接口的路由,路径中的{id}为路径参数,Body为请求体的类型,用来生成OpenAPI文档
+type restRoute struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	RPC     string //对应的RPC方法,用于检查API令牌的权限
+	Query   []restParam
+	Body    interface{}
+	Public  bool //不需要登录
+	Handle  func(r restRequest) response
+}
+
+//restRequest 一次REST请求,ctx和hprose的上下文一样包含用户名和IP,可以直接调用RPC的方法
+type restRequest struct {
+	ctx  rpc.Context
+	req  *http.Request
+	vars map[string]string
+}
+
+func (r restRequest) id() int64 {
+	id, _ := strconv.ParseInt(r.vars["id"], 10, 64)
+	return id
+}
+
+func (r restRequest) int64(name string, value int64) int64 {
+	if v, err := strconv.ParseInt(r.req.URL.Query().Get(name), 10, 64); err == nil {
+		return v
+	}
+	return value
+}
+
+func (r restRequest) string(name, value string) string {
+	if v := r.req.URL.Query().Get(name); v != "" {
+		return v
+	}
+	return value
+}
+
+func (r restRequest) strings(name string) (values []string) {
+	for _, v := range strings.Split(r.req.URL.Query().Get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+func (r restRequest) bool(name string) bool {
+	v, _ := strconv.ParseBool(r.req.URL.Query().Get(name))
+	return v
+}
+
+//decode 解析JSON请求体,失败时返回错误信息
+func (r restRequest) decode(v interface{}) string {
+	if err := json.NewDecoder(r.req.Body).Decode(v); err != nil {
+		return fmt.Sprint("Invalid request body: ", err)
+	}
+	return ""
+}
+
+//restLogin 登录的请求体
+type restLogin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type restToken struct {
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	ExpiresAt int64  `json:"expiresAt"` //过期时间(毫秒),0表示不过期
+}
+
+//分页参数
+var restPageParams = []restParam{
+	{Name: "size", Type: "integer", Description: "page size, default 20, -1 means all"},
+	{Name: "page", Type: "integer", Description: "page number, default 1"},
+	{Name: "order", Type: "string", Description: "order by, default \"id\""},
+}
+
+var restRoutes = []restRoute{
+	{
+		Method: "POST", Path: "/login", RPC: "User.Login", Tag: "user", Summary: "Login and get the token", Public: true,
+		Body: restLogin{},
+		Handle: func(r restRequest) (resp response) {
+			req := restLogin{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			return user{}.Login(req.Username, req.Password, r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/user", RPC: "User.Get", Tag: "user", Summary: "Get the current user",
+		Handle: func(r restRequest) response {
+			return user{}.Get("", r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/algorithms", RPC: "Algorithm.List", Tag: "algorithm", Summary: "List the algorithms", Query: restPageParams,
+		Handle: func(r restRequest) response {
+			return algorithm{}.List(r.int64("size", 20), r.int64("page", 1), r.string("order", "id"), r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/algorithms", RPC: "Algorithm.Put", Tag: "algorithm", Summary: "Create an algorithm", Body: model.Algorithm{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Algorithm{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = 0
+			return algorithm{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "PUT", Path: "/algorithms/{id}", RPC: "Algorithm.Put", Tag: "algorithm", Summary: "Update an algorithm", Body: model.Algorithm{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Algorithm{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = r.id()
+			return algorithm{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/algorithms/{id}", RPC: "Algorithm.Delete", Tag: "algorithm", Summary: "Delete an algorithm",
+		Handle: func(r restRequest) response {
+			return algorithm{}.Delete([]int64{r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/algorithms/{id}/versions", RPC: "Algorithm.Versions", Tag: "algorithm", Summary: "List the versions of an algorithm", Query: restPageParams[:2],
+		Handle: func(r restRequest) response {
+			return algorithm{}.Versions(r.id(), r.int64("size", 20), r.int64("page", 1), r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/traders", RPC: "Trader.List", Tag: "trader", Summary: "List the traders of an algorithm",
+		Query: []restParam{{Name: "algorithmId", Type: "integer", Description: "the algorithm of the traders"}},
+		Handle: func(r restRequest) response {
+			return runner{}.List(r.int64("algorithmId", 0), r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/traders", RPC: "Trader.Put", Tag: "trader", Summary: "Create a trader", Body: model.Trader{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Trader{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = 0
+			return runner{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "PUT", Path: "/traders/{id}", RPC: "Trader.Put", Tag: "trader", Summary: "Update a trader", Body: model.Trader{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Trader{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = r.id()
+			return runner{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/traders/{id}", RPC: "Trader.Delete", Tag: "trader", Summary: "Delete a trader",
+		Handle: func(r restRequest) response {
+			return runner{}.Delete(model.Trader{ID: r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/traders/{id}/status", RPC: "Trader.Status", Tag: "trader", Summary: "Get the status of a trader",
+		Handle: func(r restRequest) response {
+			return runner{}.Status(model.Trader{ID: r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/traders/{id}/switch", RPC: "Trader.Switch", Tag: "trader", Summary: "Start or stop a trader",
+		Handle: func(r restRequest) response {
+			return runner{}.Switch(model.Trader{ID: r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/traders/{id}/logs", RPC: "Log.List", Tag: "log", Summary: "List the logs of a trader",
+		Query: []restParam{
+			{Name: "pageSize", Type: "integer", Description: "page size, default 20"},
+			{Name: "current", Type: "integer", Description: "page number, default 1"},
+			{Name: "cursor", Type: "string", Description: "the cursor returned by the previous page"},
+			{Name: "type", Type: "array", Description: "log types"},
+			{Name: "exchangeType", Type: "array", Description: "exchange types"},
+			{Name: "stockType", Type: "array", Description: "stock types"},
+			{Name: "from", Type: "integer", Description: "start time (milliseconds)"},
+			{Name: "to", Type: "integer", Description: "end time (milliseconds)"},
+			{Name: "message", Type: "string", Description: "keywords of the message"},
+		},
+		Handle: func(r restRequest) response {
+			return logger{}.List(model.Trader{ID: r.id()}, pagination{
+				PageSize: r.int64("pageSize", 20),
+				Current:  r.int64("current", 1),
+			}, filters{
+				Type:         r.strings("type"),
+				ExchangeType: r.strings("exchangeType"),
+				StockType:    r.strings("stockType"),
+				From:         r.int64("from", 0),
+				To:           r.int64("to", 0),
+				Message:      r.string("message", ""),
+				Cursor:       r.string("cursor", ""),
+			}, r.ctx)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/traders/{id}/logs", RPC: "Log.Clear", Tag: "log", Summary: "Delete the logs of a trader",
+		Query: []restParam{{Name: "before", Type: "integer", Description: "delete the logs before this time (milliseconds), 0 means before the last run"}},
+		Handle: func(r restRequest) response {
+			return logger{}.Clear(model.Trader{ID: r.id()}, r.int64("before", 0), r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/traders/{id}/profits", RPC: "Log.Profits", Tag: "log", Summary: "Get the profit curve and the performance statistics of a trader",
+		Query: []restParam{
+			{Name: "from", Type: "integer", Description: "start time (milliseconds)"},
+			{Name: "to", Type: "integer", Description: "end time (milliseconds)"},
+			{Name: "points", Type: "integer", Description: "max points of the curve"},
+		},
+		Handle: func(r restRequest) response {
+			return logger{}.Profits(model.Trader{ID: r.id()}, r.int64("from", 0), r.int64("to", 0), int(r.int64("points", 0)), r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/exchanges", RPC: "Exchange.List", Tag: "exchange", Summary: "List the exchanges", Query: restPageParams,
+		Handle: func(r restRequest) response {
+			return exchange{}.List(r.int64("size", 20), r.int64("page", 1), r.string("order", "id"), r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/exchanges", RPC: "Exchange.Put", Tag: "exchange", Summary: "Create an exchange", Body: model.Exchange{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Exchange{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = 0
+			return exchange{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "PUT", Path: "/exchanges/{id}", RPC: "Exchange.Put", Tag: "exchange", Summary: "Update an exchange", Body: model.Exchange{},
+		Handle: func(r restRequest) (resp response) {
+			req := model.Exchange{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			req.ID = r.id()
+			return exchange{}.Put(req, r.ctx)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/exchanges/{id}", RPC: "Exchange.Delete", Tag: "exchange", Summary: "Delete an exchange",
+		Handle: func(r restRequest) response {
+			return exchange{}.Delete([]int64{r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/exchanges/{id}/test", RPC: "Exchange.Test", Tag: "exchange", Summary: "Check the saved keys of an exchange",
+		Handle: func(r restRequest) response {
+			return exchange{}.Test(model.Exchange{ID: r.id()}, r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/exchanges/{id}/audits", RPC: "Manual.Audits", Tag: "exchange", Summary: "List the audits of the manual operations on an exchange", Query: restPageParams[:2],
+		Handle: func(r restRequest) response {
+			return manual{}.Audits(r.id(), r.int64("size", 20), r.int64("page", 1), r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/account/overview", RPC: "Account.Overview", Tag: "account", Summary: "Get the consolidated account of all the exchanges",
+		Query: []restParam{
+			{Name: "quote", Type: "string", Description: "the quote currency, USDT or BTC"},
+			{Name: "refresh", Type: "boolean", Description: "ignore the cache"},
+		},
+		Handle: func(r restRequest) response {
+			return account{}.Overview(r.string("quote", "USDT"), r.bool("refresh"), r.ctx)
+		},
+	},
+	{
+		Method: "GET", Path: "/tokens", RPC: "Token.List", Tag: "token", Summary: "List the API tokens",
+		Handle: func(r restRequest) response {
+			return apiToken{}.List("", r.ctx)
+		},
+	},
+	{
+		Method: "POST", Path: "/tokens", RPC: "Token.Put", Tag: "token", Summary: "Create an API token, the secret is returned only once", Body: restToken{},
+		Handle: func(r restRequest) (resp response) {
+			req := restToken{}
+			if resp.Message = r.decode(&req); resp.Message != "" {
+				return
+			}
+			return apiToken{}.Put(req.Name, req.Scope, req.ExpiresAt, r.ctx)
+		},
+	},
+	{
+		Method: "DELETE", Path: "/tokens/{id}", RPC: "Token.Delete", Tag: "token", Summary: "Revoke an API token",
+		Handle: func(r restRequest) response {
+			return apiToken{}.Delete([]int64{r.id()}, r.ctx)
+		},
+	},
+}
+
+//match 匹配路由的路径,返回路径参数
+func (route restRoute) match(path string) (vars map[string]string, ok bool) {
+	parts := strings.Split(strings.Trim(route.Path, "/"), "/")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != len(segments) {
+		return nil, false
+	}
+	vars = make(map[string]string)
+	for i, p := range parts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			vars[strings.Trim(p, "{}")] = segments[i]
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+//restStatus 把RPC的响应转换为HTTP状态码
+func restStatus(resp response) int {
+	switch {
+	case resp.Success:
+		return http.StatusOK
+	case resp.Message == constant.ErrAuthorizationError:
+		return http.StatusUnauthorized
+	case resp.Message == constant.ErrInsufficientPermissions:
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func restWrite(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+//rest 处理/v1下的REST请求,认证方式和/api一样使用Authorization: Bearer <token>,token可以是API令牌
+func rest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, restPrefix)
+	if r.Method == "GET" && path == "/openapi.json" {
+		restWrite(w, http.StatusOK, openAPI())
+		return
+	}
+	start := time.Now()
+	found := false
+	for _, route := range restRoutes {
+		vars, ok := route.match(path)
+		if !ok {
+			continue
+		}
+		found = true
+		if route.Method != r.Method {
+			continue
+		}
+		ctx := rpc.NewBaseContext()
+		username, scope := authorize(r.Header.Get("Authorization"))
+		ctx.SetString("username", username)
+		ctx.SetString("scope", scope)
+		ctx.SetString("ip", remoteIP(r))
+		resp := response{Message: constant.ErrAuthorizationError}
+		if !route.Public && username != "" && !scopeAllows(scope, route.RPC) {
+			resp.Message = constant.ErrInsufficientPermissions
+		} else if route.Public || username != "" {
+			resp = route.Handle(restRequest{ctx: ctx, req: r, vars: vars})
+		}
+		restWrite(w, restStatus(resp), resp)
+		log.Printf("%16s %v spend %v", r.Method, r.URL.Path, time.Since(start))
+		return
+	}
+	if found {
+		restWrite(w, http.StatusMethodNotAllowed, response{Message: "Method Not Allowed"})
+		return
+	}
+	restWrite(w, http.StatusNotFound, response{Message: "Not Found"})
+}
+
+//openAPI 根据路由定义生成OpenAPI 3.0文档
+func openAPI() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Response": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"Success": map[string]interface{}{"type": "boolean"},
+				"Message": map[string]interface{}{"type": "string"},
+				"Data":    map[string]interface{}{},
+			},
+		},
+	}
+	paths := make(map[string]map[string]interface{})
+	for _, route := range restRoutes {
+		parameters := []interface{}{}
+		for _, p := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+				parameters = append(parameters, map[string]interface{}{
+					"name":     strings.Trim(p, "{}"),
+					"in":       "path",
+					"required": true,
+					"schema":   map[string]interface{}{"type": "integer"},
+				})
+			}
+		}
+		for _, q := range route.Query {
+			schema := map[string]interface{}{"type": q.Type}
+			if q.Type == "array" {
+				schema["items"] = map[string]interface{}{"type": "string"}
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name":        q.Name,
+				"in":          "query",
+				"description": q.Description,
+				"schema":      schema,
+				"style":       "form",
+				"explode":     false,
+			})
+		}
+		operation := map[string]interface{}{
+			"tags":       []string{route.Tag},
+			"summary":    route.Summary,
+			"parameters": parameters,
+			"responses": map[string]interface{}{
+				"default": map[string]interface{}{
+					"description": "Success is false and Message is the error when the status is not 200",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/Response"},
+						},
+					},
+				},
+			},
+		}
+		if route.Public {
+			operation["security"] = []interface{}{}
+		}
+		if route.Body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": jsonSchema(reflect.TypeOf(route.Body), schemas),
+					},
+				},
+			}
+		}
+		if paths[restPrefix+route.Path] == nil {
+			paths[restPrefix+route.Path] = make(map[string]interface{})
+		}
+		paths[restPrefix+route.Path][strings.ToLower(route.Method)] = operation
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "QuantBot",
+			"version": constant.Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "the token returned by /login or an API token starting with " + model.TokenPrefix,
+				},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearer": []string{}}},
+	}
+}
+
+//jsonSchema 根据Go类型和json标签生成JSON Schema,命名的结构体放到schemas中引用,避免循环引用
+func jsonSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t.Name() != "" {
+			if _, ok := schemas[t.Name()]; !ok {
+				schemas[t.Name()] = map[string]interface{}{} //先占位,结构体引用自身时不会无限递归
+				schemas[t.Name()] = structSchema(t, schemas)
+			}
+			return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		}
+		return structSchema(t, schemas)
+	}
+	return map[string]interface{}{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		//没有json名称的嵌入结构体,字段和json编码一样展开到外层
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range structSchema(f.Type, schemas)["properties"].(map[string]interface{}) {
+				properties[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = jsonSchema(f.Type, schemas)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
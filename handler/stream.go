@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/trader"
+)
+
+//推送的参数
+const (
+	streamStatusInterval = time.Second      //检查策略状态和刷新策略列表的间隔
+	streamPingInterval   = 15 * time.Second //没有数据时发送注释行,避免连接被代理断开
+	streamLogBatch       = 500              //每次查询的最大日志数量
+)
+
+//streamLog 推送的日志,包含所属的策略
+type streamLog struct {
+	TraderID int64 `json:"traderId"`
+	model.Log
+}
+
+//streamStatus 推送的策略状态
+type streamStatus struct {
+	TraderID int64 `json:"traderId"`
+	trader.Status
+}
+
+//streamState 推送的运行状态变化
+type streamState struct {
+	TraderID int64 `json:"traderId"`
+	Status   int64 `json:"status"`
+}
+
+//sseWriter 按照Server-Sent Events的格式发送事件
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s sseWriter) send(id, event string, data interface{}) error {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %v\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %v\ndata: %s\n\n", event, bs); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+//streamTraders 用户可以查看的策略,指定了策略ID时只包含该策略
+func streamTraders(self model.User, id int64) (ids []int64, err error) {
+	if id > 0 {
+		t, err := self.GetTrader(id)
+		if err != nil {
+			return nil, err
+		}
+		return []int64{t.ID}, nil
+	}
+	traders, err := self.ListAllTrader()
+	if err != nil {
+		return
+	}
+	for _, t := range traders {
+		ids = append(ids, t.ID)
+	}
+	return
+}
+
+//stream 通过Server-Sent Events推送新的日志(log)、策略状态(status)和运行状态的变化(state)
+//
+//推送的内容都是只读的,任何权限的API令牌都可以使用。浏览器的EventSource不能设置请求头,token可以通过URL参数传递;断线重连时浏览器自动发送Last-Event-ID,
+//从该日志ID之后继续推送,也可以通过URL参数lastId指定
+func stream(w http.ResponseWriter, r *http.Request) {
+	username, _ := authorize(r.Header.Get("Authorization"))
+	if username == "" {
+		username, _ = authorize(r.URL.Query().Get("token"))
+	}
+	self, err := model.GetUser(username)
+	if username == "" || err != nil {
+		http.Error(w, "Authorization Error", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	traderID, _ := strconv.ParseInt(r.URL.Query().Get("trader"), 10, 64)
+	ids, err := streamTraders(self, traderID)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusForbidden)
+		return
+	}
+	lastID, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		lastID, err = strconv.ParseInt(r.URL.Query().Get("lastId"), 10, 64)
+	}
+	if err != nil {
+		//没有指定时只推送连接之后的新日志
+		if lastID, err = self.LastLogID(ids); err != nil {
+			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+			return
+		}
+	}
+	watcher, cancel := model.WatchLog()
+	defer cancel()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	sse := sseWriter{w: w, flusher: flusher}
+	statuses := make(map[int64]trader.Status)
+	statusTicker := time.NewTicker(streamStatusInterval)
+	defer statusTicker.Stop()
+	pingTicker := time.NewTicker(streamPingInterval)
+	defer pingTicker.Stop()
+	sendLogs := func() error {
+		for {
+			logs, err := self.ListLogAfter(ids, lastID, streamLogBatch)
+			if err != nil {
+				return err
+			}
+			for _, l := range logs {
+				if err := sse.send(fmt.Sprint(l.ID), "log", streamLog{TraderID: l.TraderID, Log: l}); err != nil {
+					return err
+				}
+				lastID = l.ID
+			}
+			if len(logs) < streamLogBatch {
+				return nil
+			}
+		}
+	}
+	sendStatus := func() error {
+		for _, id := range ids {
+			status := trader.GetStatus(id)
+			old, ok := statuses[id]
+			if !ok || old.Status != status.Status {
+				if err := sse.send("", "state", streamState{TraderID: id, Status: status.Status}); err != nil {
+					return err
+				}
+			}
+			//心跳时间一直在变化,只在状态或者定时器变化时推送
+			if !ok || old.Status != status.Status || !reflect.DeepEqual(old.Timers, status.Timers) {
+				if err := sse.send("", "status", streamStatus{TraderID: id, Status: status}); err != nil {
+					return err
+				}
+			}
+			statuses[id] = status
+		}
+		return nil
+	}
+	if err := sendLogs(); err != nil {
+		return
+	}
+	if err := sendStatus(); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-watcher:
+			err = sendLogs()
+		case <-statusTicker.C:
+			if traderID == 0 {
+				//新建的策略在下一次检查时加入推送
+				if newIDs, err := streamTraders(self, 0); err == nil {
+					ids = newIDs
+				}
+			}
+			err = sendStatus()
+		case <-pingTicker.C:
+			if _, err = fmt.Fprint(w, ": ping\n\n"); err == nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			log.Println("Stream to", username, "closed:", err)
+			return
+		}
+	}
+}
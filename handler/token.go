@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+type apiToken struct{}
+
+//令牌权限的等级,高等级包含低等级的全部权限
+var tokenScopeLevels = map[string]int{
+	constant.TokenScopeRead:  1,
+	constant.TokenScopeTrade: 2,
+	constant.TokenScopeAdmin: 3,
+}
+
+//各个方法需要的令牌权限,没有列出的方法需要admin权限
+var tokenMethodScopes = map[string]string{
+	"User.Login":         constant.TokenScopeRead,
+	"User.Get":           constant.TokenScopeRead,
+	"Exchange.Types":     constant.TokenScopeRead,
+	"Exchange.List":      constant.TokenScopeRead,
+	"Account.Quotes":     constant.TokenScopeRead,
+	"Account.Overview":   constant.TokenScopeRead,
+	"Account.History":    constant.TokenScopeRead,
+	"Algorithm.List":     constant.TokenScopeRead,
+	"Algorithm.Versions": constant.TokenScopeRead,
+	"Algorithm.Diff":     constant.TokenScopeRead,
+	"Library.List":       constant.TokenScopeRead,
+	"Notify.Types":       constant.TokenScopeRead,
+	"Notify.List":        constant.TokenScopeRead,
+	"Chart.List":         constant.TokenScopeRead,
+	"Chart.Series":       constant.TokenScopeRead,
+	"Trader.List":        constant.TokenScopeRead,
+	"Trader.Status":      constant.TokenScopeRead,
+	"Trader.Audits":      constant.TokenScopeRead,
+	"Log.List":           constant.TokenScopeRead,
+	"Log.Debug":          constant.TokenScopeRead,
+	"Log.Profits":        constant.TokenScopeRead,
+	"Log.Writer":         constant.TokenScopeRead,
+	"Manual.Account":     constant.TokenScopeRead,
+	"Manual.Ticker":      constant.TokenScopeRead,
+	"Manual.Orders":      constant.TokenScopeRead,
+	"Manual.Audits":      constant.TokenScopeRead,
+	"Token.Scopes":       constant.TokenScopeRead,
+	"Token.List":         constant.TokenScopeRead,
+	"Trader.Switch":      constant.TokenScopeTrade,
+	"Manual.Trade":       constant.TokenScopeTrade,
+	"Manual.Cancel":      constant.TokenScopeTrade,
+}
+
+//hideExchangeKeys 使用API令牌访问时不返回交易所的密钥,密钥只能通过登录的管理台查看
+func hideExchangeKeys(ctx rpc.Context, exchanges []model.Exchange) {
+	if ctx.GetString("scope") == "" {
+		return
+	}
+	for i := range exchanges {
+		exchanges[i].AccessKey, exchanges[i].SecretKey = "", ""
+	}
+}
+
+//scopeAllows 令牌的权限是否可以调用该方法,scope为空表示登录获取的JWT,不限制
+func scopeAllows(scope, method string) bool {
+	if scope == "" {
+		return true
+	}
+	required, ok := tokenMethodScopes[method]
+	if !ok {
+		required = constant.TokenScopeAdmin
+	}
+	return tokenScopeLevels[scope] >= tokenScopeLevels[required]
+}
+
+//authorize 解析Authorization请求头,支持登录获取的JWT和API令牌,返回用户名和令牌的权限
+func authorize(header string) (username, scope string) {
+	token := strings.TrimPrefix(header, "Bearer ")
+	if !strings.HasPrefix(token, model.TokenPrefix) {
+		return parseToken(token), ""
+	}
+	user, t, err := model.GetAPIToken(token)
+	if err != nil {
+		return "", ""
+	}
+	return user.Username, t.Scope
+}
+
+// Scopes list the scopes of API tokens
+func (apiToken) Scopes(_ string, ctx rpc.Context) (resp response) {
+	resp.Data = constant.TokenScopes
+	resp.Success = true
+	return
+}
+
+// List list the API tokens of the user, the secrets are not included
+func (apiToken) List(_ string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	tokens, err := self.ListAPIToken()
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = tokens
+	resp.Success = true
+	return
+}
+
+// Put create an API token which expires at expiresAt (milliseconds, 0 means never),
+// the secret is returned only once
+func (apiToken) Put(name, scope string, expiresAt int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if name == "" {
+		resp.Message = "Name can not be empty"
+		return
+	}
+	if _, ok := tokenScopeLevels[scope]; !ok {
+		resp.Message = fmt.Sprint("Unsupported scope ", scope)
+		return
+	}
+	//令牌的权限不能超过创建它的令牌
+	if current := ctx.GetString("scope"); current != "" && tokenScopeLevels[scope] > tokenScopeLevels[current] {
+		resp.Message = constant.ErrInsufficientPermissions
+		return
+	}
+	var expires *time.Time
+	if expiresAt > 0 {
+		t := time.Unix(0, expiresAt*int64(time.Millisecond))
+		if t.Before(time.Now()) {
+			resp.Message = "The expiry time has passed"
+			return
+		}
+		expires = &t
+	}
+	token, secret, err := self.CreateAPIToken(name, scope, expires)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Token  model.APIToken
+		Secret string
+	}{
+		Token:  token,
+		Secret: secret,
+	}
+	resp.Success = true
+	return
+}
+
+// Delete revoke the API tokens of the user
+func (apiToken) Delete(ids []int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if err := self.DeleteAPIToken(ids); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Success = true
+	return
+}
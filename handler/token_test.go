@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hprose/hprose-golang/rpc"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+func TestReadTokenHidesExchangeKeys(t *testing.T) {
+	admin, err := model.GetUser("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.DB.Create(&model.Exchange{UserID: admin.ID, Name: "keys", Type: constant.Binance, AccessKey: "access", SecretKey: "secret"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, secret, err := admin.CreateAPIToken("read", constant.TokenScopeRead, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(rest))
+	defer server.Close()
+	req, _ := http.NewRequest("GET", server.URL+"/v1/exchanges", nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	resp := struct {
+		Success bool
+		Data    struct {
+			List []model.Exchange
+		}
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil || !resp.Success || len(resp.Data.List) == 0 {
+		t.Fatalf("list exchanges with a read token: %v, %+v", err, resp)
+	}
+	for _, e := range resp.Data.List {
+		if e.AccessKey != "" || e.SecretKey != "" {
+			t.Fatalf("read token can see the keys of exchange %v", e.ID)
+		}
+	}
+	//登录获取的token仍然可以看到密钥
+	ctx := rpc.NewBaseContext()
+	ctx.SetString("username", admin.Username)
+	list := exchange{}.List(-1, 1, "id", ctx).Data.(struct {
+		Total int64
+		List  []model.Exchange
+	}).List
+	if len(list) == 0 || list[0].SecretKey != "secret" {
+		t.Fatalf("login user can not see the keys: %+v", list)
+	}
+}
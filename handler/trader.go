@@ -2,6 +2,7 @@ package handler
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/hprose/hprose-golang/rpc"
 	"github.com/phonegapX/QuantBot/constant"
@@ -30,6 +31,9 @@ func (runner) List(algorithmID int64, ctx rpc.Context) (resp response) {
 	}
 	for i, t := range traders {
 		traders[i].Status = trader.GetTraderStatus(t.ID)
+		traders[i].NextStartAt, traders[i].NextStopAt = trader.NextSchedule(t)
+		traders[i].Heartbeat = trader.GetHeartbeatAge(t.ID)
+		hideExchangeKeys(ctx, traders[i].Exchanges)
 	}
 	resp.Data = traders
 	resp.Success = true
@@ -48,6 +52,10 @@ func (runner) Put(req model.Trader, ctx rpc.Context) (resp response) {
 		resp.Message = fmt.Sprint(err)
 		return
 	}
+	if err := trader.CheckSchedule(req); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
 	db, err := model.NewOrm()
 	if err != nil {
 		resp.Message = fmt.Sprint(err)
@@ -136,3 +144,89 @@ func (runner) Switch(req model.Trader, ctx rpc.Context) (resp response) {
 	resp.Success = true
 	return
 }
+
+// Status
+func (runner) Status(req model.Trader, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if req, err = self.GetTrader(req.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = trader.GetStatus(req.ID)
+	resp.Success = true
+	return
+}
+
+// Eval
+func (runner) Eval(req model.Trader, expression string, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	if self.Level < constant.AdminLevel {
+		resp.Message = constant.ErrInsufficientPermissions
+		return
+	}
+	if req, err = self.GetTrader(req.ID); err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	result, err := trader.Eval(req.ID, expression)
+	audit := result
+	if err != nil {
+		audit = fmt.Sprint("Error: ", err)
+	}
+	if err := self.CreateAudit(req.ID, "Eval", expression, audit); err != nil {
+		log.Println("Create audit error:", err)
+	}
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = result
+	resp.Success = true
+	return
+}
+
+// Audits
+func (runner) Audits(req model.Trader, size, page int64, ctx rpc.Context) (resp response) {
+	username := ctx.GetString("username")
+	if username == "" {
+		resp.Message = constant.ErrAuthorizationError
+		return
+	}
+	self, err := model.GetUser(username)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	total, audits, err := self.ListAudit(req.ID, size, page)
+	if err != nil {
+		resp.Message = fmt.Sprint(err)
+		return
+	}
+	resp.Data = struct {
+		Total int64
+		List  []model.Audit
+	}{
+		Total: total,
+		List:  audits,
+	}
+	resp.Success = true
+	return
+}
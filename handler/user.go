@@ -2,10 +2,12 @@ package handler
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/hprose/hprose-golang/rpc"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
+	"github.com/phonegapX/QuantBot/notify"
 )
 
 type user struct{}
@@ -28,6 +30,14 @@ func (user) Login(username, password string, ctx rpc.Context) (resp response) {
 		resp.Success = true
 	} else {
 		resp.Message = "Make token error"
+		return
+	}
+	if ip := ctx.GetString("ip"); ip != "" {
+		if known, err := user.CreateLoginRecord(ip); err != nil {
+			log.Println("Create login record error:", err)
+		} else if !known {
+			go notify.Event(user, constant.EventNewLoginIP, "User "+user.Username, fmt.Sprintf("Login from a new IP %v", ip))
+		}
 	}
 	return
 }
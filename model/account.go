@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// AccountSnapshot struct
+type AccountSnapshot struct {
+	ID        int64     `gorm:"primary_key;AUTO_INCREMENT" json:"id"`
+	UserID    int64     `gorm:"index" json:"userId"`
+	Quote     string    `gorm:"type:varchar(20)" json:"quote"` //计价货币
+	Total     float64   `json:"total"`                         //所有交易所资产的总估值
+	Detail    string    `gorm:"type:text" json:"detail"`       //JSON格式的各交易所和各资产的估值
+	CreatedAt time.Time `gorm:"index" json:"createdAt"`
+}
+
+// ListOwnExchange list the exchanges which the user owns
+func (user User) ListOwnExchange() (exchanges []Exchange, err error) {
+	err = DB.Where("user_id = ?", user.ID).Find(&exchanges).Error
+	return
+}
+
+// SaveAccountSnapshot ...
+func SaveAccountSnapshot(snapshot *AccountSnapshot) error {
+	return DB.Create(snapshot).Error
+}
+
+// ListAccountSnapshot list the account snapshots in the quote currency between from and to (milliseconds, 0 means unlimited)
+func (user User) ListAccountSnapshot(quote string, from, to int64) (snapshots []AccountSnapshot, err error) {
+	query := DB.Where("user_id = ? AND quote = ?", user.ID, quote)
+	if from > 0 {
+		query = query.Where("created_at >= ?", time.Unix(0, from*int64(time.Millisecond)))
+	}
+	if to > 0 {
+		query = query.Where("created_at <= ?", time.Unix(0, to*int64(time.Millisecond)))
+	}
+	err = query.Order("created_at").Find(&snapshots).Error
+	return
+}
+
+// ListAccountUser list the users who own at least one exchange
+func ListAccountUser() (users []User, err error) {
+	userIDs := []int64{}
+	if err = DB.Model(&Exchange{}).Pluck("DISTINCT user_id", &userIDs).Error; err != nil || len(userIDs) == 0 {
+		return
+	}
+	err = DB.Where("id IN (?)", userIDs).Find(&users).Error
+	return
+}
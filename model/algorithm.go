@@ -1,7 +1,11 @@
 package model
 
 import (
+	"fmt"
+	"log"
 	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
 )
 
 // Algorithm struct
@@ -11,12 +15,28 @@ type Algorithm struct {
 	Name        string     `gorm:"type:varchar(200)" json:"name"`
 	Description string     `gorm:"type:text" json:"description"`
 	Script      string     `gorm:"type:text" json:"script"`
+	Engine      string     `gorm:"type:varchar(20)" json:"engine"` //脚本引擎,为空时使用otto
 	EvnDefault  string     `gorm:"type:text" json:"evnDefault"`
 	CreatedAt   time.Time  `json:"createdAt"`
 	UpdatedAt   time.Time  `json:"updatedAt"`
 	DeletedAt   *time.Time `sql:"index" json:"-"`
 
 	Traders []Trader `gorm:"-" json:"traders"`
+	Comment string   `gorm:"-" json:"comment"` //保存时的版本说明
+}
+
+// AlgorithmVersion struct
+type AlgorithmVersion struct {
+	ID          int64     `gorm:"primary_key" json:"id"`
+	AlgorithmID int64     `gorm:"unique_index:idx_algorithm_version" json:"algorithmId"`
+	Version     int64     `gorm:"unique_index:idx_algorithm_version" json:"version"`
+	UserID      int64     `json:"userId"`
+	Author      string    `gorm:"type:varchar(25)" json:"author"`
+	Comment     string    `gorm:"type:varchar(200)" json:"comment"`
+	Script      string    `gorm:"type:text" json:"script"`
+	Engine      string    `gorm:"type:varchar(20)" json:"engine"`
+	EvnDefault  string    `gorm:"type:text" json:"evnDefault"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 // ListAlgorithm ...
@@ -39,3 +59,132 @@ func (user User) ListAlgorithm(size, page int64, order string) (total int64, alg
 	err = DB.Where("user_id in (?)", userIDs).Order(toUnderScoreCase(order)).Limit(size).Offset((page - 1) * size).Find(&algorithms).Error
 	return
 }
+
+// GetAlgorithm ...
+func (user User) GetAlgorithm(id interface{}) (algorithm Algorithm, err error) {
+	if err = DB.Where("id = ?", id).First(&algorithm).Error; err != nil {
+		return
+	}
+	_, users, err := user.ListUser(-1, 1, "id")
+	if err != nil {
+		return
+	}
+	for _, u := range users {
+		if u.ID == algorithm.UserID {
+			return
+		}
+	}
+	err = fmt.Errorf(constant.ErrInsufficientPermissions)
+	return
+}
+
+//同时保存同一个策略时,版本号冲突的一方重试的次数
+const algorithmVersionRetry = 3
+
+// SaveAlgorithm save the algorithm and a new version of it in one transaction, the algorithm is created when its ID is 0,
+// the version numbers are unique for each algorithm
+func (user User) SaveAlgorithm(algorithm *Algorithm, comment string) (version AlgorithmVersion, err error) {
+	id := algorithm.ID
+	for i := 0; i < algorithmVersionRetry; i++ {
+		if version, err = user.saveAlgorithm(algorithm, comment); err == nil {
+			return
+		}
+		//事务回滚后新建的算法并不存在,重试时重新创建
+		algorithm.ID = id
+	}
+	return
+}
+
+//saveAlgorithm 在事务中保存算法,读取最新的版本号并保存新版本,唯一索引保证并发保存时不会产生重复的版本号
+func (user User) saveAlgorithm(algorithm *Algorithm, comment string) (version AlgorithmVersion, err error) {
+	db := DB.Begin()
+	if err = db.Save(algorithm).Error; err != nil {
+		db.Rollback()
+		return
+	}
+	last := []AlgorithmVersion{}
+	if err = db.Where("algorithm_id = ?", algorithm.ID).Order("version desc").Limit(1).Find(&last).Error; err != nil {
+		db.Rollback()
+		return
+	}
+	version = AlgorithmVersion{
+		AlgorithmID: algorithm.ID,
+		Version:     1,
+		UserID:      user.ID,
+		Author:      user.Username,
+		Comment:     comment,
+		Script:      algorithm.Script,
+		Engine:      algorithm.Engine,
+		EvnDefault:  algorithm.EvnDefault,
+	}
+	if len(last) > 0 {
+		version.Version = last[0].Version + 1
+	}
+	if err = db.Create(&version).Error; err != nil {
+		db.Rollback()
+		return
+	}
+	err = db.Commit().Error
+	return
+}
+
+//backfillAlgorithmVersion 为版本管理之前创建的算法保存当前内容作为第1个版本,否则无法固定版本和回滚
+func backfillAlgorithmVersion() {
+	algorithms := []Algorithm{}
+	if err := DB.Where("id NOT IN (?)", DB.Model(&AlgorithmVersion{}).Select("algorithm_id").QueryExpr()).Find(&algorithms).Error; err != nil {
+		log.Println("Backfill algorithm versions error:", err)
+		return
+	}
+	for _, a := range algorithms {
+		owner := User{}
+		DB.First(&owner, a.UserID)
+		version := AlgorithmVersion{
+			AlgorithmID: a.ID,
+			Version:     1,
+			UserID:      a.UserID,
+			Author:      owner.Username,
+			Comment:     "Initial version",
+			Script:      a.Script,
+			Engine:      a.Engine,
+			EvnDefault:  a.EvnDefault,
+			CreatedAt:   a.UpdatedAt,
+		}
+		if err := DB.Create(&version).Error; err != nil {
+			log.Printf("Backfill the version of algorithm %v error: %v\n", a.ID, err)
+		}
+	}
+}
+
+// ListAlgorithmVersion ...
+func (user User) ListAlgorithmVersion(algorithmID, size, page int64) (total int64, versions []AlgorithmVersion, err error) {
+	if _, err = user.GetAlgorithm(algorithmID); err != nil {
+		return
+	}
+	err = DB.Model(&AlgorithmVersion{}).Where("algorithm_id = ?", algorithmID).Count(&total).Error
+	if err != nil {
+		return
+	}
+	if size == -1 {
+		size = 1000
+	}
+	err = DB.Where("algorithm_id = ?", algorithmID).Order("version desc").Limit(size).Offset((page - 1) * size).Find(&versions).Error
+	return
+}
+
+// GetAlgorithmVersion ...
+func GetAlgorithmVersion(algorithmID, version int64) (algorithmVersion AlgorithmVersion, err error) {
+	err = DB.Where("algorithm_id = ? AND version = ?", algorithmID, version).First(&algorithmVersion).Error
+	return
+}
+
+// LastAlgorithmVersion ...
+func LastAlgorithmVersion(algorithmID int64) (version int64, err error) {
+	last := []AlgorithmVersion{}
+	if err = DB.Where("algorithm_id = ?", algorithmID).Order("version desc").Limit(1).Find(&last).Error; err != nil {
+		return
+	}
+	if len(last) > 0 {
+		version = last[0].Version
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"time"
+)
+
+// Audit struct
+type Audit struct {
+	ID         int64     `gorm:"primary_key;AUTO_INCREMENT" json:"id"`
+	UserID     int64     `gorm:"index" json:"userId"`
+	Username   string    `gorm:"type:varchar(25)" json:"username"`
+	TraderID   int64     `gorm:"index" json:"traderId"`
+	ExchangeID int64     `gorm:"index" json:"exchangeId"` //手动交易使用的交易所
+	Action     string    `gorm:"type:varchar(50)" json:"action"`
+	Detail     string    `gorm:"type:text" json:"detail"`
+	Result     string    `gorm:"type:text" json:"result"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CreateAudit ...
+func (user User) CreateAudit(traderID int64, action, detail, result string) (err error) {
+	audit := Audit{
+		UserID:   user.ID,
+		Username: user.Username,
+		TraderID: traderID,
+		Action:   action,
+		Detail:   detail,
+		Result:   result,
+	}
+	return DB.Create(&audit).Error
+}
+
+// CreateExchangeAudit save an audit of a manual operation on the exchange
+func (user User) CreateExchangeAudit(exchangeID int64, action, detail, result string) (err error) {
+	audit := Audit{
+		UserID:     user.ID,
+		Username:   user.Username,
+		ExchangeID: exchangeID,
+		Action:     action,
+		Detail:     detail,
+		Result:     result,
+	}
+	return DB.Create(&audit).Error
+}
+
+// ListExchangeAudit list the audits of the manual operations on the exchange
+func (user User) ListExchangeAudit(exchangeID, size, page int64) (total int64, audits []Audit, err error) {
+	if _, err = user.GetExchange(exchangeID); err != nil {
+		return
+	}
+	query := DB.Model(&Audit{}).Where("exchange_id = ? AND trader_id = ?", exchangeID, 0)
+	if err = query.Count(&total).Error; err != nil {
+		return
+	}
+	if size == -1 {
+		size = 1000
+	}
+	err = query.Order("id desc").Limit(size).Offset((page - 1) * size).Find(&audits).Error
+	return
+}
+
+// ListAudit ...
+func (user User) ListAudit(traderID, size, page int64) (total int64, audits []Audit, err error) {
+	if _, err = user.GetTrader(traderID); err != nil {
+		return
+	}
+	err = DB.Model(&Audit{}).Where("trader_id = ?", traderID).Count(&total).Error
+	if err != nil {
+		return
+	}
+	if size == -1 {
+		size = 1000
+	}
+	err = DB.Where("trader_id = ?", traderID).Order("id desc").Limit(size).Offset((page - 1) * size).Find(&audits).Error
+	return
+}
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+//降采样时每次按X查询的最大数量
+const chartPointChunk = 500
+
+// ChartLayout struct
+type ChartLayout struct {
+	ID        int64         `gorm:"primary_key" json:"id"`
+	TraderID  int64         `gorm:"index" json:"traderId"`
+	Name      string        `gorm:"type:varchar(100)" json:"name"`
+	Title     string        `gorm:"type:varchar(200)" json:"title"`
+	Layout    string        `gorm:"type:text" json:"-"` //JSON格式的Series
+	Series    []ChartSeries `gorm:"-" json:"series"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// ChartSeries is a series declared in a chart layout
+type ChartSeries struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`    //line、bar、scatter或candlestick
+	Overlay string `json:"overlay"` //叠加显示在同一个图表中的另一个系列,例如K线上的均线
+	Color   string `json:"color"`
+}
+
+// ChartPoint struct
+type ChartPoint struct {
+	ID       int64   `gorm:"primary_key;AUTO_INCREMENT" json:"-"`
+	TraderID int64   `gorm:"index:idx_chart_point" json:"-"`
+	Chart    string  `gorm:"type:varchar(100);index:idx_chart_point" json:"-"`
+	Series   string  `gorm:"type:varchar(100);index:idx_chart_point" json:"-"`
+	X        int64   `gorm:"index:idx_chart_point" json:"x"` //默认为毫秒时间戳
+	Y        float64 `json:"y"`                              //K线的收盘价
+	Open     float64 `json:"open,omitempty"`
+	High     float64 `json:"high,omitempty"`
+	Low      float64 `json:"low,omitempty"`
+}
+
+// SaveChartLayout create or replace the layout of a chart of the trader
+func SaveChartLayout(layout ChartLayout) (err error) {
+	bs, err := json.Marshal(layout.Series)
+	if err != nil {
+		return
+	}
+	old := []ChartLayout{}
+	if err = DB.Where("trader_id = ? AND name = ?", layout.TraderID, layout.Name).Limit(1).Find(&old).Error; err != nil {
+		return
+	}
+	if len(old) > 0 {
+		layout.ID = old[0].ID
+	}
+	layout.Layout = string(bs)
+	return DB.Save(&layout).Error
+}
+
+// SaveChartPoint queue a point, it is written in batches by the log writer
+func SaveChartPoint(point ChartPoint) {
+	defaultLogWriter.pushPoint(point)
+}
+
+// ListChartLayout list the chart layouts of a trader, a chart which has points but no layout is listed as line series
+func (user User) ListChartLayout(traderID int64) (layouts []ChartLayout, err error) {
+	if _, err = user.GetTrader(traderID); err != nil {
+		return
+	}
+	if err = DB.Where("trader_id = ?", traderID).Order("name").Find(&layouts).Error; err != nil {
+		return
+	}
+	declared := make(map[string]bool)
+	for i, l := range layouts {
+		declared[l.Name] = true
+		json.Unmarshal([]byte(l.Layout), &layouts[i].Series)
+	}
+	rows := []struct {
+		Chart  string
+		Series string
+	}{}
+	if err = DB.Model(&ChartPoint{}).Select("DISTINCT chart, series").Where("trader_id = ?", traderID).Order("chart, series").Scan(&rows).Error; err != nil {
+		return
+	}
+	for _, r := range rows {
+		if declared[r.Chart] {
+			continue
+		}
+		if len(layouts) == 0 || layouts[len(layouts)-1].Name != r.Chart || layouts[len(layouts)-1].ID != 0 {
+			layouts = append(layouts, ChartLayout{TraderID: traderID, Name: r.Chart, Title: r.Chart})
+		}
+		l := &layouts[len(layouts)-1]
+		l.Series = append(l.Series, ChartSeries{Name: r.Series, Type: constant.ChartLine})
+	}
+	return
+}
+
+// ListChartSeries list the points of every series of a chart between from and to, the oldest first,
+// from/to 0 means unlimited, a series which has more than size points is downsampled to about size points
+func (user User) ListChartSeries(traderID int64, chart string, from, to int64, size int) (series map[string][]ChartPoint, err error) {
+	layouts, err := user.ListChartLayout(traderID)
+	if err != nil {
+		return
+	}
+	types := make(map[string]string)
+	for _, l := range layouts {
+		if l.Name == chart {
+			for _, s := range l.Series {
+				types[s.Name] = s.Type
+			}
+		}
+	}
+	query := DB.Model(&ChartPoint{}).Where("trader_id = ? AND chart = ?", traderID, chart)
+	if from > 0 {
+		query = query.Where("x >= ?", from)
+	}
+	if to > 0 {
+		query = query.Where("x <= ?", to)
+	}
+	ranges := []struct {
+		Series string
+		First  int64
+		Last   int64
+		Count  int
+	}{}
+	if err = query.Select("series, MIN(x) AS first, MAX(x) AS last, COUNT(*) AS count").Group("series").Scan(&ranges).Error; err != nil {
+		return
+	}
+	series = make(map[string][]ChartPoint)
+	for _, r := range ranges {
+		query := query.Where("series = ?", r.Series)
+		points := []ChartPoint{}
+		if size <= 0 || r.Count <= size {
+			err = query.Order("x, id").Find(&points).Error
+		} else {
+			points, err = downsample(query, r.First, r.Last, types[r.Series], size)
+		}
+		if err != nil {
+			return
+		}
+		series[r.Series] = points
+	}
+	return
+}
+
+//downsample 在数据库中把X轴平均分为size段,每段合并为一个点:K线合并开高低收,信号点保留每段的第一个点,其他系列保留每段的最后一个点
+func downsample(query *gorm.DB, first, last int64, typ string, size int) (points []ChartPoint, err error) {
+	width := (last-first)/int64(size) + 1
+	div := "/"
+	if DB.Dialect().GetName() == "mysql" {
+		div = "DIV"
+	}
+	bucket := fmt.Sprintf("(x - %d) %v %d", first, div, width)
+	buckets := []struct {
+		First int64
+		Last  int64
+		High  float64
+		Low   float64
+	}{}
+	if err = query.Select("MIN(x) AS first, MAX(x) AS last, MAX(high) AS high, MIN(low) AS low").Group(bucket).Order("first").Scan(&buckets).Error; err != nil {
+		return
+	}
+	//取出每段第一个和最后一个X上的点,每次最多查询chartPointChunk个X
+	xs := []int64{}
+	for _, b := range buckets {
+		if xs = append(xs, b.First); b.Last != b.First {
+			xs = append(xs, b.Last)
+		}
+	}
+	byX := make(map[int64][]ChartPoint)
+	for i := 0; i < len(xs); i += chartPointChunk {
+		end := i + chartPointChunk
+		if end > len(xs) {
+			end = len(xs)
+		}
+		chunk := []ChartPoint{}
+		if err = query.Where("x IN (?)", xs[i:end]).Order("x, id").Find(&chunk).Error; err != nil {
+			return
+		}
+		for _, p := range chunk {
+			byX[p.X] = append(byX[p.X], p)
+		}
+	}
+	for _, b := range buckets {
+		firsts, lasts := byX[b.First], byX[b.Last]
+		if len(firsts) == 0 || len(lasts) == 0 {
+			continue
+		}
+		p := lasts[len(lasts)-1]
+		switch typ {
+		case constant.ChartCandlestick:
+			p.Open, p.High, p.Low = firsts[0].Open, b.High, b.Low
+		case constant.ChartScatter:
+			p = firsts[0]
+		}
+		points = append(points, p)
+	}
+	return
+}
@@ -1,7 +1,10 @@
 package model
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
 )
 
 // Exchange struct
@@ -15,6 +18,27 @@ type Exchange struct {
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
 	DeletedAt *time.Time `sql:"index" json:"-"`
+	Test      *bool      `gorm:"-" json:"test,omitempty"` //保存前是否检查密钥,nil表示使用exchangeTestOnSave的配置
+}
+
+// GetExchange get an exchange which belongs to the user or the users managed by the user
+func (user User) GetExchange(id int64) (exchange Exchange, err error) {
+	_, users, err := user.ListUser(-1, 1, "id")
+	if err != nil {
+		return
+	}
+	userIDs := []int64{}
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+	}
+	exchanges := []Exchange{}
+	if err = DB.Where("id = ? AND user_id in (?)", id, userIDs).Limit(1).Find(&exchanges).Error; err != nil {
+		return
+	}
+	if len(exchanges) == 0 {
+		return exchange, fmt.Errorf(constant.ErrInsufficientPermissions)
+	}
+	return exchanges[0], nil
 }
 
 // ListExchange ...
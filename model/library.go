@@ -0,0 +1,151 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+// Library struct
+type Library struct {
+	ID          int64      `gorm:"primary_key" json:"id"`
+	UserID      int64      `gorm:"index" json:"userId"`
+	Name        string     `gorm:"type:varchar(200);index" json:"name"`
+	Description string     `gorm:"type:text" json:"description"`
+	Script      string     `gorm:"type:text" json:"script"`
+	Version     int64      `json:"version"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `sql:"index" json:"-"`
+}
+
+// LibraryVersion struct
+type LibraryVersion struct {
+	ID        int64     `gorm:"primary_key" json:"id"`
+	LibraryID int64     `gorm:"index" json:"libraryId"`
+	Version   int64     `gorm:"index" json:"version"`
+	Script    string    `gorm:"type:text" json:"script"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ListLibrary ...
+func (user User) ListLibrary(size, page int64, order string) (total int64, libraries []Library, err error) {
+	_, users, err := user.ListUser(-1, 1, "id")
+	if err != nil {
+		return
+	}
+	userIDs := []int64{}
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+	}
+	err = DB.Model(&Library{}).Where("user_id in (?)", userIDs).Count(&total).Error
+	if err != nil {
+		return
+	}
+	if size == -1 {
+		size = 1000
+	}
+	err = DB.Where("user_id in (?)", userIDs).Order(toUnderScoreCase(order)).Limit(size).Offset((page - 1) * size).Find(&libraries).Error
+	return
+}
+
+// GetLibrary get a library by id
+func (user User) GetLibrary(id interface{}) (library Library, err error) {
+	if err = DB.Where("id = ?", id).First(&library).Error; err != nil {
+		return
+	}
+	_, users, err := user.ListUser(-1, 1, "id")
+	if err != nil {
+		return
+	}
+	for _, u := range users {
+		if u.ID == library.UserID {
+			return
+		}
+	}
+	err = fmt.Errorf(constant.ErrInsufficientPermissions)
+	return
+}
+
+// FindLibrary get the script of a library by name, the library of the user itself is preferred,
+// version 0 means the latest version
+func (user User) FindLibrary(name string, version int64) (library Library, err error) {
+	_, users, err := user.ListUser(-1, 1, "id")
+	if err != nil {
+		return
+	}
+	userIDs := []int64{}
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+	}
+	libraries := []Library{}
+	if err = DB.Where("name = ? AND user_id in (?)", name, userIDs).Find(&libraries).Error; err != nil {
+		return
+	}
+	if len(libraries) == 0 {
+		err = fmt.Errorf("Can not found the library %v", name)
+		return
+	}
+	library = libraries[0]
+	for _, l := range libraries {
+		if l.UserID == user.ID {
+			library = l
+		}
+	}
+	if version <= 0 || version == library.Version {
+		return
+	}
+	v := LibraryVersion{}
+	if err = DB.Where("library_id = ? AND version = ?", library.ID, version).First(&v).Error; err != nil {
+		err = fmt.Errorf("Can not found the library %v version %v", name, version)
+		return
+	}
+	library.Script = v.Script
+	library.Version = v.Version
+	return
+}
+
+// SaveLibrary create or update a library, every save creates a new version
+func (user User) SaveLibrary(req Library) (library Library, err error) {
+	if req.ID > 0 {
+		if library, err = user.GetLibrary(req.ID); err != nil {
+			return
+		}
+	} else {
+		library.UserID = user.ID
+	}
+	count := int64(0)
+	if err = DB.Model(&Library{}).Where("name = ? AND user_id = ? AND id <> ?", req.Name, library.UserID, library.ID).Count(&count).Error; err != nil {
+		return
+	}
+	if count > 0 {
+		err = fmt.Errorf("The library %v already exists", req.Name)
+		return
+	}
+	library.Name = req.Name
+	library.Description = req.Description
+	library.Script = req.Script
+	library.Version++
+	db, err := NewOrm()
+	if err != nil {
+		return
+	}
+	defer db.Close()
+	db = db.Begin()
+	if err = db.Save(&library).Error; err != nil {
+		db.Rollback()
+		return
+	}
+	version := LibraryVersion{
+		LibraryID: library.ID,
+		Version:   library.Version,
+		Script:    library.Script,
+	}
+	if err = db.Create(&version).Error; err != nil {
+		db.Rollback()
+		return
+	}
+	err = db.Commit().Error
+	return
+}
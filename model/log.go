@@ -3,37 +3,184 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"reflect"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/jinzhu/gorm"
 	"github.com/phonegapX/QuantBot/constant"
 )
 
 // Log struct
 type Log struct {
 	ID           int64   `gorm:"primary_key;AUTO_INCREMENT" json:"id"`
-	TraderID     int64   `gorm:"index" json:"-"`
-	Timestamp    int64   `json:"-"`
+	TraderID     int64   `gorm:"index:idx_logs_trader_timestamp" json:"-"`
+	Timestamp    int64   `gorm:"index:idx_logs_trader_timestamp" json:"-"`
 	ExchangeType string  `gorm:"type:varchar(50)" json:"exchangeType"`
 	Type         string  `json:"type"` // [-1"error", 0"info", 1"profit", 2"buy", 3"sell", 4"cancel", 5"long", 6"short", 7"long_close", 8"short_close"]
 	StockType    string  `gorm:"type:varchar(20)" json:"stockType"`
 	Price        float64 `json:"price"`
 	Amount       float64 `json:"amount"`
 	Message      string  `gorm:"type:text" json:"message"`
+	Detail       string  `gorm:"type:text" json:"detail"` //结构化的附加信息,例如脚本错误的堆栈和源码
 
 	Time time.Time `gorm:"-" json:"time"`
 }
 
-// ListLog ...
-func (user User) ListLog(id, size, page int64) (total int64, logs []Log, err error) {
-	err = DB.Model(&Log{}).Where("trader_id = ?", id).Count(&total).Error
+// LogFilter is the conditions of listing logs
+type LogFilter struct {
+	Type         []string
+	ExchangeType []string
+	StockType    []string
+	From         int64  //毫秒时间戳,0表示不限制
+	To           int64  //毫秒时间戳,0表示不限制
+	Message      string //消息中需要包含的文本,多个关键字用空格分隔,需要全部包含
+	Cursor       string //上一页返回的游标,不为空时按游标翻页,不再统计总数
+}
+
+//likeEscaper 转义LIKE中的通配符
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+//logFullText MySQL的日志消息是否有ngram全文索引,ngram的长度为2
+var logFullText bool
+
+//indexLogMessage 为日志消息的搜索创建索引:MySQL使用ngram全文索引,Postgres使用pg_trgm索引LOWER(message) LIKE,
+//SQLite没有可用的索引,搜索会扫描策略在时间范围内的全部日志,创建失败时同样退回到扫描
+func indexLogMessage() {
+	table := DB.NewScope(&Log{}).TableName()
+	switch DB.Dialect().GetName() {
+	case "mysql":
+		if !DB.Dialect().HasIndex(table, "idx_logs_message") {
+			//关闭停用词,否则包含停用词的ngram不会被索引,关键字会搜索不到
+			tx := DB.Begin()
+			err := tx.Exec("SET SESSION innodb_ft_enable_stopword = OFF").Error
+			if err == nil {
+				err = tx.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX idx_logs_message ON %v (message) WITH PARSER ngram", table)).Error
+			}
+			tx.Rollback()
+			if err != nil {
+				log.Println("Create full-text index of logs error:", err)
+				return
+			}
+		}
+		logFullText = true
+	case "postgres":
+		err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+		if err == nil {
+			err = DB.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_logs_message ON %v USING gin (LOWER(message) gin_trgm_ops)", table)).Error
+		}
+		if err != nil {
+			log.Println("Create trigram index of logs error:", err)
+		}
+	}
+}
+
+//where 根据过滤条件生成查询
+func (filter LogFilter) where(query *gorm.DB) *gorm.DB {
+	if len(filter.Type) > 0 {
+		query = query.Where("type IN (?)", filter.Type)
+	}
+	if len(filter.ExchangeType) > 0 {
+		query = query.Where("exchange_type IN (?)", filter.ExchangeType)
+	}
+	if len(filter.StockType) > 0 {
+		query = query.Where("stock_type IN (?)", filter.StockType)
+	}
+	if filter.From > 0 {
+		query = query.Where("timestamp >= ?", filter.From*int64(time.Millisecond))
+	}
+	if filter.To > 0 {
+		query = query.Where("timestamp <= ?", filter.To*int64(time.Millisecond))
+	}
+	for _, word := range strings.Fields(filter.Message) {
+		//全文索引先找出包含关键字的ngram短语的日志,再用LIKE保证和没有索引时的结果一致
+		if phrase := strings.Replace(word, `"`, "", -1); logFullText && utf8.RuneCountInString(phrase) >= 2 {
+			query = query.Where("MATCH (message) AGAINST (? IN BOOLEAN MODE)", `"`+phrase+`"`)
+		}
+		query = query.Where("LOWER(message) LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(strings.ToLower(word))+"%")
+	}
+	return query
+}
+
+//logCursor 游标由最后一条日志的时间戳和ID组成,日志按时间戳和ID倒序排列
+func logCursor(l Log) string {
+	return fmt.Sprintf("%v-%v", l.Timestamp, l.ID)
+}
+
+func parseLogCursor(cursor string) (timestamp, id int64, err error) {
+	if _, err = fmt.Sscanf(cursor, "%d-%d", &timestamp, &id); err != nil {
+		err = fmt.Errorf("Invalid cursor %v", cursor)
+	}
+	return
+}
+
+// ListLog list the logs of a trader which match the filter,
+// cursor is used to get the next page, it is empty when there are no more logs
+func (user User) ListLog(id int64, filter LogFilter, size, page int64) (total int64, logs []Log, cursor string, err error) {
+	if size == -1 {
+		size = 1000
+	}
+	query := filter.where(DB.Model(&Log{}).Where("trader_id = ? AND type <> ?", id, constant.DEBUG))
+	if filter.Cursor == "" {
+		if err = query.Count(&total).Error; err != nil {
+			return
+		}
+		query = query.Offset((page - 1) * size)
+	} else {
+		timestamp, logID, err := parseLogCursor(filter.Cursor)
+		if err != nil {
+			return 0, nil, "", err
+		}
+		query = query.Where("timestamp < ? OR (timestamp = ? AND id < ?)", timestamp, timestamp, logID)
+	}
+	if err = query.Order("timestamp desc, id desc").Limit(size).Find(&logs).Error; err != nil {
+		return
+	}
+	for i, l := range logs {
+		logs[i].Time = time.Unix(0, l.Timestamp)
+	}
+	if int64(len(logs)) == size {
+		cursor = logCursor(logs[len(logs)-1])
+	}
+	return
+}
+
+// ListLogAfter list at most size logs of the traders after the log id, the oldest first
+func (user User) ListLogAfter(traderIDs []int64, after, size int64) (logs []Log, err error) {
+	if len(traderIDs) == 0 {
+		return
+	}
+	err = DB.Where("trader_id IN (?) AND id > ?", traderIDs, after).Order("id").Limit(size).Find(&logs).Error
+	for i, l := range logs {
+		logs[i].Time = time.Unix(0, l.Timestamp)
+	}
+	return
+}
+
+// LastLogID get the id of the latest log of the traders
+func (user User) LastLogID(traderIDs []int64) (id int64, err error) {
+	logs := []Log{}
+	if len(traderIDs) == 0 {
+		return
+	}
+	if err = DB.Where("trader_id IN (?)", traderIDs).Order("id desc").Limit(1).Find(&logs).Error; err != nil || len(logs) == 0 {
+		return
+	}
+	return logs[0].ID, nil
+}
+
+// ListDebugLog list the Console output of a trader
+func (user User) ListDebugLog(id, size, page int64) (total int64, logs []Log, err error) {
+	err = DB.Model(&Log{}).Where("trader_id = ? AND type = ?", id, constant.DEBUG).Count(&total).Error
 	if err != nil {
 		return
 	}
 	if size == -1 {
 		size = 1000
 	}
-	err = DB.Where("trader_id = ?", id).Order("timestamp desc, id desc").Limit(size).Offset((page - 1) * size).Find(&logs).Error
+	err = DB.Where("trader_id = ? AND type = ?", id, constant.DEBUG).Order("timestamp desc, id desc").Limit(size).Offset((page - 1) * size).Find(&logs).Error
 	for i, l := range logs {
 		logs[i].Time = time.Unix(0, l.Timestamp)
 	}
@@ -44,36 +191,55 @@ func (user User) ListLog(id, size, page int64) (total int64, logs []Log, err err
 type Logger struct {
 	TraderID     int64
 	ExchangeType string
+	Hook         func(Log) //每条日志放入写入队列之前调用
 }
 
 // Log ...
 func (l Logger) Log(method string, stockType string, price, amount float64, messages ...interface{}) {
-	now := time.Now().UnixNano()
-	go func(now int64) {
-		message := ""
-		for _, m := range messages {
-			if method != constant.ERROR {
-				v := reflect.ValueOf(m)
-				switch v.Kind() {
-				case reflect.Struct, reflect.Map, reflect.Slice:
-					if bs, err := json.Marshal(m); err == nil {
-						message += string(bs)
-						continue
-					}
+	l.log(method, stockType, price, amount, "", messages...)
+}
+
+// LogDetail save a log with the structured detail, the detail is stored as JSON
+func (l Logger) LogDetail(method string, detail interface{}, messages ...interface{}) {
+	bs, err := json.Marshal(detail)
+	if err != nil {
+		bs = []byte(fmt.Sprint(detail))
+	}
+	l.log(method, "", 0.0, 0.0, string(bs), messages...)
+}
+
+func (l Logger) log(method string, stockType string, price, amount float64, detail string, messages ...interface{}) {
+	message := ""
+	for _, m := range messages {
+		if method != constant.ERROR {
+			v := reflect.ValueOf(m)
+			switch v.Kind() {
+			case reflect.Struct, reflect.Map, reflect.Slice:
+				if bs, err := json.Marshal(m); err == nil {
+					message += string(bs)
+					continue
 				}
 			}
-			message += fmt.Sprintf("%+v", m)
 		}
-		log := Log{
-			TraderID:     l.TraderID,
-			Timestamp:    now,
-			ExchangeType: l.ExchangeType,
-			Type:         method,
-			StockType:    stockType,
-			Price:        price,
-			Amount:       amount,
-			Message:      message,
-		}
-		DB.Create(&log)
-	}(now)
+		message += fmt.Sprintf("%+v", m)
+	}
+	log := Log{
+		TraderID:     l.TraderID,
+		Timestamp:    time.Now().UnixNano(),
+		ExchangeType: l.ExchangeType,
+		Type:         method,
+		StockType:    stockType,
+		Price:        price,
+		Amount:       amount,
+		Message:      message,
+		Detail:       detail,
+	}
+	if l.Hook != nil {
+		l.Hook(log)
+	}
+	//不属于任何策略的日志(手动交易、账户总览和密钥检查)只交给Hook处理,不保存
+	if l.TraderID == 0 {
+		return
+	}
+	defaultLogWriter.push(log)
 }
@@ -0,0 +1,243 @@
+package model
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+)
+
+//日志写入的默认参数
+const (
+	logDefaultQueueSize     = 10000
+	logDefaultFlushInterval = 200 //毫秒
+	logBatchSize            = 100 //每条INSERT语句最多写入的日志或图表数据点,9列*100行不超过SQLite的参数数量限制
+)
+
+//日志队列满时的处理方式
+const (
+	logPolicyBlock = "block" //等待队列有空位,策略的日志调用会被阻塞
+	logPolicyDrop  = "drop"  //丢弃新的日志并计数
+)
+
+var (
+	defaultLogWriter = newLogWriter()
+	logWatchers      = make(map[chan struct{}]bool) //日志写入后需要通知的监听者
+	logWatchersMutex sync.Mutex
+)
+
+// LogWriterStats is the counters of the log writer
+type LogWriterStats struct {
+	Queued  int64 //队列中等待写入的日志
+	Written int64
+	Dropped int64 //队列满时丢弃的日志和图表数据点
+	Failed  int64 //写入数据库失败的日志和图表数据点
+	Points  int64 //写入的图表数据点
+}
+
+//所有策略的日志通过一个队列按顺序写入,同一个策略的日志顺序和调用顺序一致,图表数据点使用另一个队列同样分批写入
+type logWriter struct {
+	queue   chan Log
+	points  chan ChartPoint
+	flush   chan chan struct{}
+	policy  string
+	written int64
+	dropped int64
+	failed  int64
+	pointed int64
+}
+
+func newLogWriter() *logWriter {
+	size := config.Int("logqueuesize", logDefaultQueueSize)
+	if size <= 0 {
+		size = logDefaultQueueSize
+	}
+	policy := strings.ToLower(config.String("logqueuepolicy"))
+	if policy != logPolicyDrop {
+		policy = logPolicyBlock
+	}
+	return &logWriter{
+		queue:  make(chan Log, size),
+		points: make(chan ChartPoint, size),
+		flush:  make(chan chan struct{}),
+		policy: policy,
+	}
+}
+
+//push 把日志放入队列,队列满时按照配置阻塞或者丢弃
+func (w *logWriter) push(l Log) {
+	if w.policy == logPolicyDrop {
+		select {
+		case w.queue <- l:
+		default:
+			w.drop()
+		}
+		return
+	}
+	w.queue <- l
+}
+
+//pushPoint 把图表数据点放入队列,队列满时的处理方式和日志相同
+func (w *logWriter) pushPoint(p ChartPoint) {
+	if w.policy == logPolicyDrop {
+		select {
+		case w.points <- p:
+		default:
+			w.drop()
+		}
+		return
+	}
+	w.points <- p
+}
+
+func (w *logWriter) drop() {
+	if atomic.AddInt64(&w.dropped, 1)%1000 == 1 {
+		log.Println("Log queue is full, dropped logs:", atomic.LoadInt64(&w.dropped))
+	}
+}
+
+//run 在数据库连接之后启动,把队列中的日志分批写入
+func (w *logWriter) run() {
+	interval := config.Int("logflushinterval", logDefaultFlushInterval)
+	if interval <= 0 {
+		interval = logDefaultFlushInterval
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+	batch := []Log{}
+	points := []ChartPoint{}
+	for {
+		select {
+		case l := <-w.queue:
+			if batch = append(batch, l); len(batch) >= logBatchSize {
+				w.write(batch)
+				batch = batch[:0]
+			}
+		case p := <-w.points:
+			if points = append(points, p); len(points) >= logBatchSize {
+				w.writePoints(points)
+				points = points[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.write(batch)
+				batch = batch[:0]
+			}
+			if len(points) > 0 {
+				w.writePoints(points)
+				points = points[:0]
+			}
+		case done := <-w.flush:
+			//写入调用FlushLog之前已经进入队列的全部日志和图表数据点
+			for n := len(w.queue); n > 0; n-- {
+				if batch = append(batch, <-w.queue); len(batch) >= logBatchSize {
+					w.write(batch)
+					batch = batch[:0]
+				}
+			}
+			if len(batch) > 0 {
+				w.write(batch)
+				batch = batch[:0]
+			}
+			for n := len(w.points); n > 0; n-- {
+				if points = append(points, <-w.points); len(points) >= logBatchSize {
+					w.writePoints(points)
+					points = points[:0]
+				}
+			}
+			if len(points) > 0 {
+				w.writePoints(points)
+				points = points[:0]
+			}
+			close(done)
+		}
+	}
+}
+
+//insert 用一条多行的INSERT语句写入一批数据,rows中每一行的值和columns一一对应
+func insert(table interface{}, columns []string, rows [][]interface{}) error {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = DB.Dialect().Quote(c)
+	}
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	placeholders := make([]string, len(rows))
+	values := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		placeholders[i] = placeholder
+		values = append(values, row...)
+	}
+	sql := fmt.Sprintf("INSERT INTO %v (%v) VALUES %v", DB.Dialect().Quote(DB.NewScope(table).TableName()), strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+	return DB.Exec(sql, values...).Error
+}
+
+//writePoints 写入一批图表数据点
+func (w *logWriter) writePoints(batch []ChartPoint) {
+	rows := make([][]interface{}, len(batch))
+	for i, p := range batch {
+		rows[i] = []interface{}{p.TraderID, p.Chart, p.Series, p.X, p.Y, p.Open, p.High, p.Low}
+	}
+	if err := insert(&ChartPoint{}, []string{"trader_id", "chart", "series", "x", "y", "open", "high", "low"}, rows); err != nil {
+		atomic.AddInt64(&w.failed, int64(len(batch)))
+		log.Printf("Write %v chart points error: %v\n", len(batch), err)
+		return
+	}
+	atomic.AddInt64(&w.pointed, int64(len(batch)))
+}
+
+//write 写入一批日志
+func (w *logWriter) write(batch []Log) {
+	rows := make([][]interface{}, len(batch))
+	for i, l := range batch {
+		rows[i] = []interface{}{l.TraderID, l.Timestamp, l.ExchangeType, l.Type, l.StockType, l.Price, l.Amount, l.Message, l.Detail}
+	}
+	if err := insert(&Log{}, []string{"trader_id", "timestamp", "exchange_type", "type", "stock_type", "price", "amount", "message", "detail"}, rows); err != nil {
+		atomic.AddInt64(&w.failed, int64(len(batch)))
+		log.Printf("Write %v logs error: %v\n", len(batch), err)
+		return
+	}
+	atomic.AddInt64(&w.written, int64(len(batch)))
+	logWatchersMutex.Lock()
+	defer logWatchersMutex.Unlock()
+	for c := range logWatchers {
+		//监听者还没有处理上一次通知时不需要重复通知
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WatchLog get a channel which is notified after new logs were written, cancel must be called when it is no longer used
+func WatchLog() (c chan struct{}, cancel func()) {
+	c = make(chan struct{}, 1)
+	logWatchersMutex.Lock()
+	logWatchers[c] = true
+	logWatchersMutex.Unlock()
+	return c, func() {
+		logWatchersMutex.Lock()
+		delete(logWatchers, c)
+		logWatchersMutex.Unlock()
+	}
+}
+
+// FlushLog write all the queued logs to the database, it is called before the server exits
+func FlushLog() {
+	done := make(chan struct{})
+	defaultLogWriter.flush <- done
+	<-done
+}
+
+// GetLogWriterStats get the counters of the log writer
+func GetLogWriterStats() LogWriterStats {
+	return LogWriterStats{
+		Queued:  int64(len(defaultLogWriter.queue)),
+		Written: atomic.LoadInt64(&defaultLogWriter.written),
+		Dropped: atomic.LoadInt64(&defaultLogWriter.dropped),
+		Failed:  atomic.LoadInt64(&defaultLogWriter.failed),
+		Points:  atomic.LoadInt64(&defaultLogWriter.pointed),
+	}
+}
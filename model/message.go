@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+)
+
+// BusMessage struct
+type BusMessage struct {
+	ID        int64     `gorm:"primary_key;AUTO_INCREMENT" json:"id"`
+	UserID    int64     `gorm:"index" json:"userId"`
+	Topic     string    `gorm:"type:varchar(200);index" json:"topic"`
+	TraderID  int64     `json:"traderId"`              //发布消息的策略
+	Data      string    `gorm:"type:text" json:"data"` //JSON格式的消息内容
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BusCursor struct
+type BusCursor struct {
+	ID        int64  `gorm:"primary_key" json:"id"`
+	TraderID  int64  `gorm:"index" json:"traderId"`
+	Topic     string `gorm:"type:varchar(200)" json:"topic"`
+	MessageID int64  `json:"messageId"` //策略已经接收的最后一条持久化消息
+}
+
+// SaveBusMessage save a message of the topic and keep only the latest keep messages
+func SaveBusMessage(message *BusMessage, keep int64) (err error) {
+	if err = DB.Create(message).Error; err != nil {
+		return
+	}
+	old := []BusMessage{}
+	err = DB.Where("user_id = ? AND topic = ?", message.UserID, message.Topic).Order("id desc").Offset(keep).Limit(1).Find(&old).Error
+	if err != nil || len(old) == 0 {
+		return
+	}
+	return DB.Where("user_id = ? AND topic = ? AND id <= ?", message.UserID, message.Topic, old[0].ID).Delete(&BusMessage{}).Error
+}
+
+// ListBusMessage list at most size messages of the topic after the message id, the oldest first
+func ListBusMessage(userID int64, topic string, after, size int64) (messages []BusMessage, err error) {
+	err = DB.Where("user_id = ? AND topic = ? AND id > ?", userID, topic, after).Order("id desc").Limit(size).Find(&messages).Error
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return
+}
+
+// LastBusMessageID get the id of the latest message of the topic
+func LastBusMessageID(userID int64, topic string) (id int64, err error) {
+	messages := []BusMessage{}
+	if err = DB.Where("user_id = ? AND topic = ?", userID, topic).Order("id desc").Limit(1).Find(&messages).Error; err != nil || len(messages) == 0 {
+		return
+	}
+	return messages[0].ID, nil
+}
+
+// GetBusCursor get the cursor of a trader on the topic, ok is false when the trader never subscribed it
+func GetBusCursor(traderID int64, topic string) (cursor BusCursor, ok bool, err error) {
+	cursors := []BusCursor{}
+	if err = DB.Where("trader_id = ? AND topic = ?", traderID, topic).Limit(1).Find(&cursors).Error; err != nil || len(cursors) == 0 {
+		return
+	}
+	return cursors[0], true, nil
+}
+
+// SaveBusCursor ...
+func SaveBusCursor(cursor *BusCursor) error {
+	return DB.Save(cursor).Error
+}
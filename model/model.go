@@ -12,6 +12,7 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
 )
 
 var (
@@ -25,8 +26,16 @@ func init() {
 	io.Register((*User)(nil), "User", "json")
 	io.Register((*Exchange)(nil), "Exchange", "json")
 	io.Register((*Algorithm)(nil), "Algorithm", "json")
+	io.Register((*AlgorithmVersion)(nil), "AlgorithmVersion", "json")
 	io.Register((*Trader)(nil), "Trader", "json")
 	io.Register((*Log)(nil), "Log", "json")
+	io.Register((*Audit)(nil), "Audit", "json")
+	io.Register((*Library)(nil), "Library", "json")
+	io.Register((*LibraryVersion)(nil), "LibraryVersion", "json")
+	io.Register((*NotifyChannel)(nil), "NotifyChannel", "json")
+	io.Register((*ChartLayout)(nil), "ChartLayout", "json")
+	io.Register((*AccountSnapshot)(nil), "AccountSnapshot", "json")
+	io.Register((*APIToken)(nil), "APIToken", "json")
 	var err error
 	DB, err = gorm.Open(strings.ToLower(dbType), dbURL)
 	if err != nil {
@@ -38,14 +47,16 @@ func init() {
 			log.Fatalln("Connect to database error:", err)
 		}
 	}
-	DB.AutoMigrate(&User{}, &Exchange{}, &Algorithm{}, &TraderExchange{}, &Trader{}, &Log{})
+	DB.AutoMigrate(&User{}, &Exchange{}, &Algorithm{}, &AlgorithmVersion{}, &TraderExchange{}, &Trader{}, &Log{}, &Audit{}, &Library{}, &LibraryVersion{}, &NotifyChannel{}, &LoginRecord{}, &BusMessage{}, &BusCursor{}, &ChartLayout{}, &ChartPoint{}, &AccountSnapshot{}, &APIToken{})
+	indexLogMessage()
+	backfillAlgorithmVersion()
 	users := []User{}
 	DB.Find(&users)
 	if len(users) == 0 {
 		admin := User{
 			Username: "admin",
 			Password: "admin",
-			Level:    99,
+			Level:    constant.AdminLevel,
 		}
 		if err := DB.Create(&admin).Error; err != nil {
 			log.Fatalln("Create admin error:", err)
@@ -53,6 +64,8 @@ func init() {
 	}
 	DB.LogMode(false)
 	go ping()
+	go pruneLogs()
+	go defaultLogWriter.run()
 }
 
 func ping() {
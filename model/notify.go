@@ -0,0 +1,94 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NotifyChannel struct
+type NotifyChannel struct {
+	ID        int64      `gorm:"primary_key" json:"id"`
+	UserID    int64      `gorm:"index" json:"userId"`
+	Name      string     `gorm:"type:varchar(50)" json:"name"`
+	Type      string     `gorm:"type:varchar(20)" json:"type"`
+	Config    string     `gorm:"type:text" json:"config"`         //渠道的JSON配置,不同类型的渠道有不同的字段
+	Events    string     `gorm:"type:varchar(200)" json:"events"` //订阅的系统事件,多个事件用逗号分隔,"*"表示所有事件
+	Enabled   bool       `json:"enabled"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `sql:"index" json:"-"`
+}
+
+// Subscribed check if the channel subscribes the system event
+func (channel NotifyChannel) Subscribed(event string) bool {
+	for _, e := range strings.Split(channel.Events, ",") {
+		if e = strings.TrimSpace(e); e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ListNotifyChannel ...
+func (user User) ListNotifyChannel(size, page int64, order string) (total int64, channels []NotifyChannel, err error) {
+	err = DB.Model(&NotifyChannel{}).Where("user_id = ?", user.ID).Count(&total).Error
+	if err != nil {
+		return
+	}
+	if size == -1 {
+		size = 1000
+	}
+	err = DB.Where("user_id = ?", user.ID).Order(toUnderScoreCase(order)).Limit(size).Offset((page - 1) * size).Find(&channels).Error
+	return
+}
+
+// GetNotifyChannel get a channel of the user by id or name
+func (user User) GetNotifyChannel(key interface{}) (channel NotifyChannel, err error) {
+	query := DB.Where("user_id = ?", user.ID)
+	if name, ok := key.(string); ok {
+		query = query.Where("name = ?", name)
+	} else {
+		query = query.Where("id = ?", key)
+	}
+	if err = query.First(&channel).Error; err != nil {
+		err = fmt.Errorf("Can not found the notify channel %v", key)
+	}
+	return
+}
+
+// ListEventChannel list the enabled channels of the user which subscribe the system event
+func (user User) ListEventChannel(event string) (channels []NotifyChannel, err error) {
+	all := []NotifyChannel{}
+	if err = DB.Where("user_id = ? AND enabled = ?", user.ID, true).Find(&all).Error; err != nil {
+		return
+	}
+	for _, c := range all {
+		if c.Subscribed(event) {
+			channels = append(channels, c)
+		}
+	}
+	return
+}
+
+// LoginRecord struct
+type LoginRecord struct {
+	ID        int64     `gorm:"primary_key" json:"id"`
+	UserID    int64     `gorm:"index" json:"userId"`
+	IP        string    `gorm:"type:varchar(50)" json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateLoginRecord save a login of the user, known is false when the user has logged in before but never from this ip
+func (user User) CreateLoginRecord(ip string) (known bool, err error) {
+	total, count := int64(0), int64(0)
+	if err = DB.Model(&LoginRecord{}).Where("user_id = ?", user.ID).Count(&total).Error; err != nil {
+		return
+	}
+	if err = DB.Model(&LoginRecord{}).Where("user_id = ? AND ip = ?", user.ID, ip).Count(&count).Error; err != nil {
+		return
+	}
+	known = total == 0 || count > 0
+	err = DB.Create(&LoginRecord{UserID: user.ID, IP: ip}).Error
+	return
+}
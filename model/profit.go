@@ -0,0 +1,207 @@
+package model
+
+import (
+	"math"
+	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+// ProfitPoint is a point of the profit curve
+type ProfitPoint struct {
+	Time   int64   //毫秒时间戳
+	Profit float64 //累计收益
+}
+
+// ProfitStats is the performance statistics of a trader
+type ProfitStats struct {
+	Curve               []ProfitPoint
+	TotalReturn         float64 //时间范围内累计收益的变化
+	MaxDrawdown         float64 //累计收益从高点回落的最大值
+	MaxDrawdownDuration int64   //最长的回撤持续时间,从高点到收复高点(或者时间范围结束),单位毫秒
+	Sharpe              float64 //按日收益计算的年化夏普比率
+	Sortino             float64 //按日收益计算的年化索提诺比率
+	Trades              int64   //收益发生变化的次数,每次变化视为一笔交易
+	WinRate             float64
+	ProfitFactor        float64 //总盈利/总亏损,没有亏损时为0
+	AverageTrade        float64
+}
+
+// ListProfitStats compute the statistics of the PROFIT logs of a trader between from and to (milliseconds, 0 means unlimited),
+// the logs are read row by row in one pass and the profit curve is downsampled to about size points
+func (user User) ListProfitStats(traderID, from, to int64, size int) (stats ProfitStats, err error) {
+	if _, err = user.GetTrader(traderID); err != nil {
+		return
+	}
+	//时间范围之前的最后一个收益作为起点
+	base := 0.0
+	if from > 0 {
+		logs := []Log{}
+		err = DB.Where("trader_id = ? AND type = ? AND timestamp < ?", traderID, constant.PROFIT, from*int64(time.Millisecond)).
+			Order("timestamp desc, id desc").Limit(1).Find(&logs).Error
+		if err != nil {
+			return
+		}
+		if len(logs) > 0 {
+			base = logs[0].Amount
+		}
+	}
+	query := DB.Model(&Log{}).Where("trader_id = ? AND type = ?", traderID, constant.PROFIT)
+	if from > 0 {
+		query = query.Where("timestamp >= ?", from*int64(time.Millisecond))
+	}
+	if to > 0 {
+		query = query.Where("timestamp <= ?", to*int64(time.Millisecond))
+	}
+	//先统计数量用来降采样,之后写入的日志不参与本次统计
+	total := struct {
+		Count int64
+		MaxID int64
+	}{}
+	if err = query.Select("COUNT(*) AS count, MAX(id) AS max_id").Scan(&total).Error; err != nil || total.Count == 0 {
+		return
+	}
+	rows, err := query.Where("id <= ?", total.MaxID).Select("timestamp, amount").Order("timestamp, id").Rows()
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	b := newProfitStatsBuilder(base, total.Count, size)
+	for rows.Next() {
+		var timestamp int64
+		var amount float64
+		if err = rows.Scan(&timestamp, &amount); err != nil {
+			return
+		}
+		b.add(ProfitPoint{Time: timestamp / int64(time.Millisecond), Profit: amount})
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	return b.stats(), nil
+}
+
+//profitStatsBuilder 逐个读入收益点计算统计,只保存降采样后的曲线和每日收益
+type profitStatsBuilder struct {
+	result ProfitStats
+	base   float64
+	count  int64 //收益点的总数
+	size   int64 //降采样后的点数
+	index  int64 //当前点的序号
+	sample int64 //下一个保留的点在降采样中的序号
+
+	peak     float64
+	peakTime int64
+	drawdown bool
+
+	last                   float64
+	grossProfit, grossLoss float64
+	wins                   int64
+
+	returns  []float64 //每日收益
+	day      int64
+	dayClose float64
+	prevDay  float64
+}
+
+func newProfitStatsBuilder(base float64, count int64, size int) *profitStatsBuilder {
+	return &profitStatsBuilder{base: base, count: count, size: int64(size), sample: 1, peak: base, last: base, prevDay: base}
+}
+
+func (b *profitStatsBuilder) add(p ProfitPoint) {
+	stats := &b.result
+	//降采样:把曲线平均分为size段,每段保留最后一个点
+	if b.size <= 0 || b.count <= b.size || b.index == b.sample*b.count/b.size-1 {
+		stats.Curve = append(stats.Curve, p)
+		b.sample++
+	}
+	first := b.index == 0
+	b.index++
+	//回撤
+	if first {
+		b.peakTime = p.Time
+	}
+	if b.drawdown || p.Profit < b.peak {
+		if d := p.Time - b.peakTime; d > stats.MaxDrawdownDuration {
+			stats.MaxDrawdownDuration = d
+		}
+	}
+	if p.Profit >= b.peak {
+		if p.Profit > b.peak || b.drawdown {
+			b.peakTime = p.Time
+		}
+		b.peak, b.drawdown = p.Profit, false
+	} else {
+		b.drawdown = true
+		if dd := b.peak - p.Profit; dd > stats.MaxDrawdown {
+			stats.MaxDrawdown = dd
+		}
+	}
+	//每笔交易
+	if change := p.Profit - b.last; change != 0 {
+		stats.Trades++
+		if change > 0 {
+			b.wins++
+			b.grossProfit += change
+		} else {
+			b.grossLoss -= change
+		}
+	}
+	b.last = p.Profit
+	//按自然日统计累计收益的变化,没有收益记录的日期收益为0
+	day := p.Time / int64(24*time.Hour/time.Millisecond)
+	if !first && day != b.day {
+		b.returns = append(b.returns, b.dayClose-b.prevDay)
+		b.prevDay = b.dayClose
+		for d := b.day + 1; d < day; d++ {
+			b.returns = append(b.returns, 0)
+		}
+	}
+	b.day, b.dayClose = day, p.Profit
+}
+
+func (b *profitStatsBuilder) stats() ProfitStats {
+	stats := b.result
+	if b.index == 0 {
+		return stats
+	}
+	stats.TotalReturn = b.last - b.base
+	if stats.Trades > 0 {
+		stats.WinRate = float64(b.wins) / float64(stats.Trades)
+		stats.AverageTrade = (b.grossProfit - b.grossLoss) / float64(stats.Trades)
+	}
+	if b.grossLoss > 0 {
+		stats.ProfitFactor = b.grossProfit / b.grossLoss
+	}
+	stats.Sharpe, stats.Sortino = ratios(append(b.returns, b.dayClose-b.prevDay))
+	return stats
+}
+
+//ratios 年化的夏普比率和索提诺比率,数字货币全年交易,按365天计算
+func ratios(returns []float64) (sharpe, sortino float64) {
+	if len(returns) < 2 {
+		return
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance, downside := 0.0, 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDeviation := math.Sqrt(downside / float64(len(returns)))
+	annual := math.Sqrt(365)
+	if std > 0 {
+		sharpe = mean / std * annual
+	}
+	if downsideDeviation > 0 {
+		sortino = mean / downsideDeviation * annual
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"log"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+const (
+	logPruneDefaultInterval = 3600   //日志清理的默认检查间隔,单位秒
+	chartKeepDefaultPoints  = 100000 //每个图表系列默认保留的数据点
+)
+
+//保留交易日志时不会被清理的日志类型
+var logTradeTypes = []string{
+	constant.PROFIT,
+	constant.BUY,
+	constant.SELL,
+	constant.LONG,
+	constant.SHORT,
+	constant.LONGCLOSE,
+	constant.SHORTCLOSE,
+}
+
+// LogRetention is the retention policy of the logs of a trader
+type LogRetention struct {
+	KeepDays   int64 //保留最近多少天的日志,0表示不限制
+	KeepRows   int64 //保留最近多少条日志,0表示不限制
+	KeepTrades bool  //总是保留收益和交易日志
+}
+
+//policy 策略的设置为0时使用全局配置,小于0时不限制
+func policy(value int64, key string) int64 {
+	if value == 0 {
+		value = int64(config.Int(key, 0))
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+// LogRetention get the retention policy of the trader, the zero settings use the global config
+func (trader Trader) LogRetention() LogRetention {
+	return LogRetention{
+		KeepDays:   policy(trader.LogKeepDays, "logkeepdays"),
+		KeepRows:   policy(trader.LogKeepRows, "logkeeprows"),
+		KeepTrades: trader.LogKeepTrades || config.String("logkeeptrades") == "true",
+	}
+}
+
+// ClearLog delete the logs of the trader before the timestamp (nanoseconds),
+// the PROFIT and trade logs are kept when the retention policy of the trader requires
+func (user User) ClearLog(traderID, before int64) (count int64, err error) {
+	trader, err := user.GetTrader(traderID)
+	if err != nil {
+		return
+	}
+	return deleteLog(trader.ID, before, 0, trader.LogRetention().KeepTrades)
+}
+
+//deleteLog 删除时间戳在before之前的日志,同一个时间戳只删除ID不大于id的日志,id为0时删除该时间戳之前的全部日志
+func deleteLog(traderID, before, id int64, keepTrades bool) (int64, error) {
+	query := DB.Where("trader_id = ?", traderID)
+	if id > 0 {
+		query = query.Where("timestamp < ? OR (timestamp = ? AND id <= ?)", before, before, id)
+	} else {
+		query = query.Where("timestamp < ?", before)
+	}
+	if keepTrades {
+		query = query.Where("type NOT IN (?)", logTradeTypes)
+	}
+	result := query.Delete(&Log{})
+	return result.RowsAffected, result.Error
+}
+
+// PruneLog enforce the retention policy of the trader
+func PruneLog(trader Trader) (count int64, err error) {
+	retention := trader.LogRetention()
+	if retention.KeepDays > 0 {
+		before := time.Now().AddDate(0, 0, -int(retention.KeepDays)).UnixNano()
+		if count, err = deleteLog(trader.ID, before, 0, retention.KeepTrades); err != nil {
+			return
+		}
+	}
+	if retention.KeepRows > 0 {
+		//找到需要保留的最旧一条日志之前的那一条,删除它和更旧的日志
+		logs := []Log{}
+		err = DB.Where("trader_id = ?", trader.ID).Order("timestamp desc, id desc").Offset(retention.KeepRows).Limit(1).Find(&logs).Error
+		if err != nil || len(logs) == 0 {
+			return
+		}
+		n, err := deleteLog(trader.ID, logs[0].Timestamp, logs[0].ID, retention.KeepTrades)
+		return count + n, err
+	}
+	return
+}
+
+// PruneChartPoint delete the oldest points of every chart series of the trader which has more than keep points
+func PruneChartPoint(traderID, keep int64) (count int64, err error) {
+	if keep <= 0 {
+		return
+	}
+	rows := []struct {
+		Chart  string
+		Series string
+	}{}
+	if err = DB.Model(&ChartPoint{}).Select("DISTINCT chart, series").Where("trader_id = ?", traderID).Scan(&rows).Error; err != nil {
+		return
+	}
+	for _, r := range rows {
+		//找到需要保留的最旧一个点之前的那一个,删除它和更旧的点
+		query := DB.Where("trader_id = ? AND chart = ? AND series = ?", traderID, r.Chart, r.Series)
+		points := []ChartPoint{}
+		if err = query.Order("x desc, id desc").Offset(keep).Limit(1).Find(&points).Error; err != nil {
+			return
+		}
+		if len(points) == 0 {
+			continue
+		}
+		result := query.Where("x < ? OR (x = ? AND id <= ?)", points[0].X, points[0].X, points[0].ID).Delete(&ChartPoint{})
+		if err = result.Error; err != nil {
+			return
+		}
+		count += result.RowsAffected
+	}
+	return
+}
+
+//pruneLogs 定期按照保留策略清理所有策略的日志和图表数据点
+func pruneLogs() {
+	for {
+		interval := config.Int("logpruneinterval", logPruneDefaultInterval)
+		if interval <= 0 {
+			interval = logPruneDefaultInterval
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+		traders := []Trader{}
+		if err := DB.Find(&traders).Error; err != nil {
+			log.Println("Prune logs error:", err)
+			continue
+		}
+		for _, t := range traders {
+			if count, err := PruneLog(t); err != nil {
+				log.Printf("Prune logs of trader %v error: %v\n", t.ID, err)
+			} else if count > 0 {
+				log.Printf("Pruned %v logs of trader %v\n", count, t.ID)
+			}
+			if count, err := PruneChartPoint(t.ID, int64(config.Int("chartkeeppoints", chartKeepDefaultPoints))); err != nil {
+				log.Printf("Prune chart points of trader %v error: %v\n", t.ID, err)
+			} else if count > 0 {
+				log.Printf("Pruned %v chart points of trader %v\n", count, t.ID)
+			}
+		}
+	}
+}
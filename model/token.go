@@ -0,0 +1,96 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+//API令牌的前缀,用来和JWT区分
+const (
+	TokenPrefix         = "qb_"
+	tokenUsedInterval   = time.Minute //最后使用时间的更新间隔,避免每次请求都写数据库
+	tokenDisplayLength  = 8           //列表中显示的令牌前几位
+	tokenSecretByteSize = 20
+)
+
+// APIToken struct
+type APIToken struct {
+	ID         int64      `gorm:"primary_key" json:"id"`
+	UserID     int64      `gorm:"index" json:"userId"`
+	Name       string     `gorm:"type:varchar(100)" json:"name"`
+	Scope      string     `gorm:"type:varchar(20)" json:"scope"`
+	Hash       string     `gorm:"type:varchar(64);unique_index" json:"-"` //令牌的SHA-256,不保存令牌本身
+	Prefix     string     `gorm:"type:varchar(20)" json:"prefix"`         //令牌的前几位,用来辨认令牌
+	ExpiresAt  *time.Time `json:"expiresAt"`                              //为空时永不过期
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken create an API token of the user, the secret is only returned here
+func (user User) CreateAPIToken(name, scope string, expiresAt *time.Time) (token APIToken, secret string, err error) {
+	bs := make([]byte, tokenSecretByteSize)
+	if _, err = rand.Read(bs); err != nil {
+		return
+	}
+	secret = TokenPrefix + hex.EncodeToString(bs)
+	token = APIToken{
+		UserID:    user.ID,
+		Name:      name,
+		Scope:     scope,
+		Hash:      hashToken(secret),
+		Prefix:    secret[:len(TokenPrefix)+tokenDisplayLength],
+		ExpiresAt: expiresAt,
+	}
+	err = DB.Create(&token).Error
+	return
+}
+
+// ListAPIToken ...
+func (user User) ListAPIToken() (tokens []APIToken, err error) {
+	err = DB.Where("user_id = ?", user.ID).Order("id desc").Find(&tokens).Error
+	return
+}
+
+// DeleteAPIToken ...
+func (user User) DeleteAPIToken(ids []int64) error {
+	return DB.Where("id in (?) AND user_id = ?", ids, user.ID).Delete(&APIToken{}).Error
+}
+
+// GetAPIToken get the user and the token by the secret, the last used time is updated
+func GetAPIToken(secret string) (user User, token APIToken, err error) {
+	if !strings.HasPrefix(secret, TokenPrefix) {
+		return user, token, fmt.Errorf("Invalid API token")
+	}
+	tokens := []APIToken{}
+	if err = DB.Where("hash = ?", hashToken(secret)).Limit(1).Find(&tokens).Error; err != nil {
+		return
+	}
+	if len(tokens) == 0 {
+		return user, token, fmt.Errorf("Invalid API token")
+	}
+	token = tokens[0]
+	now := time.Now()
+	if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+		return user, token, fmt.Errorf("API token expired")
+	}
+	if user, err = GetUserByID(token.UserID); err != nil {
+		return
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenUsedInterval {
+		token.LastUsedAt = &now
+		if err := DB.Model(&token).Update("last_used_at", now).Error; err != nil {
+			log.Println("Update API token error:", err)
+		}
+	}
+	return
+}
@@ -9,19 +9,32 @@ import (
 
 // Trader struct
 type Trader struct {
-	ID          int64      `gorm:"primary_key" json:"id"`
-	UserID      int64      `gorm:"index" json:"userId"`
-	AlgorithmID int64      `gorm:"index" json:"algorithmId"`
-	Name        string     `gorm:"type:varchar(200)" json:"name"`
-	Environment string     `gorm:"type:text" json:"environment"`
-	LastRunAt   time.Time  `json:"lastRunAt"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	UpdatedAt   time.Time  `json:"updatedAt"`
-	DeletedAt   *time.Time `sql:"index" json:"-"`
+	ID            int64      `gorm:"primary_key" json:"id"`
+	UserID        int64      `gorm:"index" json:"userId"`
+	AlgorithmID   int64      `gorm:"index" json:"algorithmId"`
+	Name          string     `gorm:"type:varchar(200)" json:"name"`
+	Environment   string     `gorm:"type:text" json:"environment"`
+	StartCron     string     `gorm:"type:varchar(100)" json:"startCron"` //自动启动的cron表达式
+	StopCron      string     `gorm:"type:varchar(100)" json:"stopCron"`  //自动停止的cron表达式
+	Timezone      string     `gorm:"type:varchar(50)" json:"timezone"`   //cron表达式的时区
+	CPULimit      int64      `json:"cpuLimit"`                           //单次循环的最长执行时间,单位毫秒,0使用默认值
+	ObjectLimit   int64      `json:"objectLimit"`                        //脚本全局可达的最大对象数量,0使用默认值
+	PinVersion    int64      `json:"pinVersion"`                         //固定运行的策略版本,0表示最新版本
+	RunVersion    int64      `json:"runVersion"`                         //最近一次启动时的策略版本
+	LogKeepDays   int64      `json:"logKeepDays"`                        //日志保留的天数,0使用默认值,小于0表示不限制
+	LogKeepRows   int64      `json:"logKeepRows"`                        //日志保留的条数,0使用默认值,小于0表示不限制
+	LogKeepTrades bool       `json:"logKeepTrades"`                      //总是保留收益和交易日志,全局配置为true时同样保留
+	LastRunAt     time.Time  `json:"lastRunAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	DeletedAt     *time.Time `sql:"index" json:"-"`
 
-	Exchanges []Exchange `gorm:"-" json:"exchanges"`
-	Status    int64      `gorm:"-" json:"status"`
-	Algorithm Algorithm  `gorm:"-" json:"algorithm"`
+	Exchanges   []Exchange `gorm:"-" json:"exchanges"`
+	Status      int64      `gorm:"-" json:"status"`
+	Algorithm   Algorithm  `gorm:"-" json:"algorithm"`
+	NextStartAt time.Time  `gorm:"-" json:"nextStartAt"`
+	NextStopAt  time.Time  `gorm:"-" json:"nextStopAt"`
+	Heartbeat   int64      `gorm:"-" json:"heartbeat"` //距离上一次心跳的毫秒数
 }
 
 // TraderExchange struct
@@ -45,6 +58,12 @@ func (user User) ListTrader(algorithmID int64) (traders []Trader, err error) {
 	return
 }
 
+// ListAllTrader list all the traders of the user
+func (user User) ListAllTrader() (traders []Trader, err error) {
+	err = DB.Where("user_id = ?", user.ID).Find(&traders).Error
+	return
+}
+
 // GetTrader ...
 func (user User) GetTrader(id interface{}) (trader Trader, err error) {
 	if err = DB.Where("id = ?", id).First(&trader).Error; err != nil {
@@ -56,6 +75,7 @@ func (user User) GetTrader(id interface{}) (trader Trader, err error) {
 	}
 	if user.Level < self.Level || user.ID != self.ID {
 		err = fmt.Errorf(constant.ErrInsufficientPermissions)
+		return
 	}
 	if trader.AlgorithmID > 0 {
 		if err = DB.Where("id = ?", trader.AlgorithmID).First(&trader.Algorithm).Error; err != nil {
@@ -98,6 +118,15 @@ func (user User) UpdateTrader(req Trader) (err error) {
 	}
 	runner.Name = req.Name
 	runner.Environment = req.Environment
+	runner.StartCron = req.StartCron
+	runner.StopCron = req.StopCron
+	runner.Timezone = req.Timezone
+	runner.CPULimit = req.CPULimit
+	runner.ObjectLimit = req.ObjectLimit
+	runner.PinVersion = req.PinVersion
+	runner.LogKeepDays = req.LogKeepDays
+	runner.LogKeepRows = req.LogKeepRows
+	runner.LogKeepTrades = req.LogKeepTrades
 	rs, err := user.GetTraderExchanges(runner.ID)
 	if err != nil {
 		db.Rollback()
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"sort"
+	"strings"
 	"unicode"
 )
 
@@ -17,3 +19,117 @@ func toUnderScoreCase(s string) string {
 	}
 	return string(out)
 }
+
+// DiffLine is a line of the diff result, Type is one of "=", "+", "-"
+type DiffLine struct {
+	Type string
+	Line string
+}
+
+// DiffLines compare two texts line by line, it uses the linear space variation of the Myers algorithm,
+// so the memory is proportional to the number of the lines
+func DiffLines(a, b string) []DiffLine {
+	diff := diffRange(strings.Split(a, "\n"), strings.Split(b, "\n"), nil)
+	//同一段修改中先输出删除的行,再输出新增的行
+	for i := 0; i < len(diff); i++ {
+		j := i
+		for j < len(diff) && diff[j].Type != "=" {
+			j++
+		}
+		hunk := diff[i:j]
+		sort.SliceStable(hunk, func(m, n int) bool {
+			return hunk[m].Type == "-" && hunk[n].Type == "+"
+		})
+		i = j
+	}
+	return diff
+}
+
+//diffRange 比较x和y并把结果追加到diff,相同的开头和结尾直接输出,其余部分从中间蛇形分割后递归比较
+func diffRange(x, y []string, diff []DiffLine) []DiffLine {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		diff = append(diff, DiffLine{Type: "=", Line: x[prefix]})
+		prefix++
+	}
+	x, y = x[prefix:], y[prefix:]
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	tail := x[len(x)-suffix:]
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+	switch {
+	case len(x) == 0:
+		for _, l := range y {
+			diff = append(diff, DiffLine{Type: "+", Line: l})
+		}
+	case len(y) == 0:
+		for _, l := range x {
+			diff = append(diff, DiffLine{Type: "-", Line: l})
+		}
+	default:
+		//去掉相同的开头和结尾后编辑距离至少为2,分割后的两部分都比原来小
+		sx, sy, ex, ey := middleSnake(x, y)
+		diff = diffRange(x[:sx], y[:sy], diff)
+		for _, l := range x[sx:ex] {
+			diff = append(diff, DiffLine{Type: "=", Line: l})
+		}
+		diff = diffRange(x[ex:], y[ey:], diff)
+	}
+	for _, l := range tail {
+		diff = append(diff, DiffLine{Type: "=", Line: l})
+	}
+	return diff
+}
+
+//middleSnake 同时从两端搜索最短编辑路径,返回两个方向相遇处的蛇形(相同的行)的起点和终点
+func middleSnake(x, y []string) (sx, sy, ex, ey int) {
+	n, m := len(x), len(y)
+	max := n + m
+	delta := n - m
+	odd := delta%2 != 0
+	//forward[k]为正向在对角线k(x-y=k)上到达的最远x,backward[k]为反向(从末尾开始)在对角线k上到达的最远距离
+	forward := make([]int, 2*max+2)
+	backward := make([]int, 2*max+2)
+	for d := 0; d <= (max+1)/2; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && forward[max+k-1] < forward[max+k+1]) {
+				i = forward[max+k+1]
+			} else {
+				i = forward[max+k-1] + 1
+			}
+			j := i - k
+			si, sj := i, j
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			forward[max+k] = i
+			//反向的对角线delta-k上已经走了d-1步,两条路径重叠时找到中间蛇形
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && i+backward[max+c] >= n {
+				return si, sj, i, j
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && backward[max+k-1] < backward[max+k+1]) {
+				i = backward[max+k+1]
+			} else {
+				i = backward[max+k-1] + 1
+			}
+			j := i - k
+			si, sj := i, j
+			for i < n && j < m && x[n-1-i] == y[m-1-j] {
+				i++
+				j++
+			}
+			backward[max+k] = i
+			if c := delta - k; !odd && c >= -d && c <= d && i+forward[max+c] >= n {
+				return n - i, m - j, n - si, m - sj
+			}
+		}
+	}
+	return 0, 0, n, m
+}
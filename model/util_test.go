@@ -0,0 +1,88 @@
+package model
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{"empty", "", "", []DiffLine{{"=", ""}}},
+		{"from empty", "", "a\nb", []DiffLine{{"-", ""}, {"+", "a"}, {"+", "b"}}},
+		{"to empty", "a\nb", "", []DiffLine{{"-", "a"}, {"-", "b"}, {"+", ""}}},
+		{"same", "a\nb", "a\nb", []DiffLine{{"=", "a"}, {"=", "b"}}},
+		{"replace", "a\nb", "c\nd", []DiffLine{{"-", "a"}, {"-", "b"}, {"+", "c"}, {"+", "d"}}},
+		{"insert", "a\nc", "a\nb\nc", []DiffLine{{"=", "a"}, {"+", "b"}, {"=", "c"}}},
+		{"insert at ends", "b", "a\nb\nc", []DiffLine{{"+", "a"}, {"=", "b"}, {"+", "c"}}},
+		{"delete", "a\nb\nc", "a\nc", []DiffLine{{"=", "a"}, {"-", "b"}, {"=", "c"}}},
+		{"delete at ends", "a\nb\nc", "b", []DiffLine{{"-", "a"}, {"=", "b"}, {"-", "c"}}},
+		{"middle", "a\nx\nb\ny\nc", "a\nb\nz\nc", []DiffLine{{"=", "a"}, {"-", "x"}, {"=", "b"}, {"-", "y"}, {"+", "z"}, {"=", "c"}}},
+	}
+	for _, c := range cases {
+		if got := DiffLines(c.a, c.b); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: DiffLines(%q, %q) = %v, want %v", c.name, c.a, c.b, got, c.want)
+		}
+	}
+	if diff := diffRange(nil, nil, nil); len(diff) != 0 {
+		t.Errorf("diffRange of empty inputs = %v", diff)
+	}
+}
+
+//lcs 用动态规划计算最长公共子序列的长度,用来检查差异是否最短
+func lcs(x, y []string) int {
+	dp := make([][]int, len(x)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] > dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	text := func() []string {
+		lines := make([]string, r.Intn(20))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		x, y := strings.Split(strings.Join(text(), "\n"), "\n"), strings.Split(strings.Join(text(), "\n"), "\n")
+		diff := DiffLines(strings.Join(x, "\n"), strings.Join(y, "\n"))
+		a, b, same := []string{}, []string{}, 0
+		for _, d := range diff {
+			if d.Type != "+" {
+				a = append(a, d.Line)
+			}
+			if d.Type != "-" {
+				b = append(b, d.Line)
+			}
+			if d.Type == "=" {
+				same++
+			}
+		}
+		if strings.Join(a, "\n") != strings.Join(x, "\n") || strings.Join(b, "\n") != strings.Join(y, "\n") || len(a) != len(x) || len(b) != len(y) {
+			t.Fatalf("diff of %v and %v does not rebuild the inputs: %v", x, y, diff)
+		}
+		if same != lcs(x, y) {
+			t.Fatalf("diff of %v and %v is not the shortest: %v", x, y, diff)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+const (
+	maxResponseSize        = 64 * 1024    //读取的最大响应长度
+	notifyDefaultSMTPPorts = "25,465,587" //配置文件中没有设置notifySMTPPorts时允许的SMTP端口
+)
+
+//发送通知的http客户端,重定向的地址同样需要在白名单中
+var client = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("Stopped after 10 redirects")
+		}
+		return checkURL(req.URL.String())
+	},
+}
+
+// Sender send a notification by a channel
+type Sender interface {
+	Send(title, message string) error
+}
+
+//所有渠道类型的构造函数,参数为渠道的JSON配置
+var makers = map[string]func(config []byte) (Sender, error){
+	constant.NotifyEmail:    newEmail,
+	constant.NotifyWebhook:  newWebhook,
+	constant.NotifyTelegram: newTelegram,
+	constant.NotifyDingTalk: newDingTalk,
+}
+
+//newSender 根据渠道的类型和配置创建Sender
+func newSender(typ, config string) (Sender, error) {
+	maker, ok := makers[typ]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported notify channel type %v", typ)
+	}
+	return maker([]byte(config))
+}
+
+//checkHost 渠道只能访问管理员在notifyAllowHosts中设置的主机
+func checkHost(host string) error {
+	if !config.HostAllowed("notifyallowhosts", host) {
+		return fmt.Errorf("Host %v is not allowed", host)
+	}
+	return nil
+}
+
+//checkSMTPPort SMTP邮件只能使用notifySMTPPorts中的端口,避免通过白名单中的主机访问其他的内部服务
+func checkSMTPPort(port int) error {
+	ports := config.String("notifysmtpports")
+	if ports == "" {
+		ports = notifyDefaultSMTPPorts
+	}
+	for _, p := range strings.Split(ports, ",") {
+		if strings.TrimSpace(p) == strconv.Itoa(port) {
+			return nil
+		}
+	}
+	return fmt.Errorf("SMTP port %v is not allowed", port)
+}
+
+//checkURL 渠道只能访问白名单中主机的http和https地址
+func checkURL(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Unsupported scheme %v", u.Scheme)
+	}
+	return checkHost(u.Hostname())
+}
+
+//postJSON 发送JSON请求,非2xx的响应作为错误,返回响应内容,错误中不包含响应内容,避免把内部服务的响应返回给用户
+func postJSON(url string, headers map[string]string, data interface{}) (body []byte, err error) {
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(bs))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("HTTP %v", resp.StatusCode)
+		return
+	}
+	body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	return
+}
+
+//SMTP邮件
+type email struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func newEmail(config []byte) (Sender, error) {
+	e := &email{Port: 25}
+	if err := json.Unmarshal(config, e); err != nil {
+		return nil, err
+	}
+	if e.Host == "" || len(e.To) == 0 {
+		return nil, fmt.Errorf("Host and To of the email channel can not be empty")
+	}
+	if err := checkHost(e.Host); err != nil {
+		return nil, err
+	}
+	if err := checkSMTPPort(e.Port); err != nil {
+		return nil, err
+	}
+	if e.From == "" {
+		e.From = e.Username
+	}
+	return e, nil
+}
+
+func (e *email) Send(title, message string) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	msg := "From: " + e.From + "\r\n" +
+		"To: " + strings.Join(e.To, ", ") + "\r\n" +
+		"Subject: " + mime.QEncoding.Encode("utf-8", title) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		message
+	return smtp.SendMail(e.Host+":"+strconv.Itoa(e.Port), auth, e.From, e.To, []byte(msg))
+}
+
+//通用的JSON webhook,POST {"title", "message", "time"}
+type webhook struct {
+	URL     string
+	Headers map[string]string
+}
+
+func newWebhook(config []byte) (Sender, error) {
+	w := &webhook{}
+	if err := json.Unmarshal(config, w); err != nil {
+		return nil, err
+	}
+	if w.URL == "" {
+		return nil, fmt.Errorf("URL of the webhook channel can not be empty")
+	}
+	if err := checkURL(w.URL); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *webhook) Send(title, message string) error {
+	_, err := postJSON(w.URL, w.Headers, map[string]interface{}{
+		"title":   title,
+		"message": message,
+		"time":    time.Now().Unix(),
+	})
+	return err
+}
+
+//Telegram机器人,API可以设置为兼容的机器人服务地址
+type telegram struct {
+	API    string
+	Token  string
+	ChatID string
+}
+
+func newTelegram(config []byte) (Sender, error) {
+	t := &telegram{API: "https://api.telegram.org"}
+	if err := json.Unmarshal(config, t); err != nil {
+		return nil, err
+	}
+	if t.Token == "" || t.ChatID == "" {
+		return nil, fmt.Errorf("Token and ChatID of the telegram channel can not be empty")
+	}
+	if err := checkURL(t.API); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *telegram) Send(title, message string) error {
+	body, err := postJSON(strings.TrimRight(t.API, "/")+"/bot"+t.Token+"/sendMessage", nil, map[string]interface{}{
+		"chat_id": t.ChatID,
+		"text":    title + "\n" + message,
+	})
+	if err != nil {
+		return err
+	}
+	result := struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram error: %v", result.Description)
+	}
+	return nil
+}
+
+//钉钉和企业微信的群机器人,它们的文本消息格式相同,钉钉设置了加签时需要填写Secret
+type dingTalk struct {
+	URL    string
+	Secret string
+}
+
+func newDingTalk(config []byte) (Sender, error) {
+	d := &dingTalk{}
+	if err := json.Unmarshal(config, d); err != nil {
+		return nil, err
+	}
+	if d.URL == "" {
+		return nil, fmt.Errorf("URL of the dingtalk channel can not be empty")
+	}
+	if err := checkURL(d.URL); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *dingTalk) Send(title, message string) error {
+	u := d.URL
+	if d.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+		mac := hmac.New(sha256.New, []byte(d.Secret))
+		mac.Write([]byte(timestamp + "\n" + d.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		u += "&timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+	body, err := postJSON(u, nil, map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": title + "\n" + message},
+	})
+	if err != nil {
+		return err
+	}
+	result := struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("Robot error %v: %v", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+// Notify Variable
+var (
+	queue        = make(chan notification, 100) //等待发送的通知
+	workers      = 4                            //同时发送通知的goroutine数量
+	rateWindow   = time.Minute
+	sent         = make(map[int64][]time.Time) //每个渠道最近一分钟内的发送时间
+	sentMutex    sync.Mutex
+	retryBackoff = time.Second //第一次重试的等待时间,之后每次加倍
+)
+
+//一条等待发送的通知
+type notification struct {
+	channel model.NotifyChannel
+	title   string
+	message string
+}
+
+func init() {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for n := range queue {
+				deliver(n)
+			}
+		}()
+	}
+}
+
+//allow 渠道每分钟的发送次数是否超过限制,允许时记录本次发送
+func allow(channel model.NotifyChannel) bool {
+	limit := config.Int("notifyratelimit", 10)
+	if limit <= 0 {
+		return true
+	}
+	sentMutex.Lock()
+	defer sentMutex.Unlock()
+	now := time.Now()
+	times := sent[channel.ID]
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= rateWindow {
+		i++
+	}
+	times = times[i:]
+	if len(times) >= limit {
+		sent[channel.ID] = times
+		return false
+	}
+	sent[channel.ID] = append(times, now)
+	return true
+}
+
+//deliver 发送一条通知,失败时按照退避时间重试
+func deliver(n notification) {
+	sender, err := newSender(n.channel.Type, n.channel.Config)
+	if err != nil {
+		log.Printf("Notify channel %v error: %v\n", n.channel.Name, err)
+		return
+	}
+	retry := config.Int("notifyretry", 3)
+	backoff := retryBackoff
+	for i := 0; ; i++ {
+		if err = sender.Send(n.title, n.message); err == nil {
+			return
+		}
+		if i >= retry {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	log.Printf("Notify channel %v error: %v\n", n.channel.Name, err)
+}
+
+// Check check the type and the config of a channel
+func Check(typ, config string) error {
+	_, err := newSender(typ, config)
+	return err
+}
+
+// Test send a notification synchronously without retry and throttling
+func Test(channel model.NotifyChannel) error {
+	sender, err := newSender(channel.Type, channel.Config)
+	if err != nil {
+		return err
+	}
+	return sender.Send("QuantBot", fmt.Sprintf("Test notification of the channel %v", channel.Name))
+}
+
+// Send queue a notification, the channel sends at most notifyRateLimit notifications in a minute
+func Send(channel model.NotifyChannel, title, message string) error {
+	if !channel.Enabled {
+		return fmt.Errorf("The notify channel %v is disabled", channel.Name)
+	}
+	if !allow(channel) {
+		return fmt.Errorf("The notify channel %v is throttled", channel.Name)
+	}
+	select {
+	case queue <- notification{channel, title, message}:
+		return nil
+	default:
+		return fmt.Errorf("Too many notifications are waiting")
+	}
+}
+
+// Event send a system event to all the channels of the user which subscribe it
+func Event(user model.User, event, title, message string) {
+	channels, err := user.ListEventChannel(event)
+	if err != nil {
+		log.Println("List notify channels error:", err)
+		return
+	}
+	for _, c := range channels {
+		if err := Send(c, fmt.Sprintf("[%v] %v", event, title), message); err != nil {
+			log.Println("Notify error:", err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phonegapX/QuantBot/api"
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+//账户总览的默认参数
+const (
+	accountDefaultCacheTTL         = 60   //秒
+	accountDefaultSnapshotInterval = 3600 //秒,0表示不保存快照
+	accountFrozenPrefix            = "Frozen"
+)
+
+// AccountQuotes is the supported quote currencies of the account overview
+var AccountQuotes = []string{"USDT", "BTC"}
+
+var (
+	accountCache      = make(map[string]AccountOverview) //用户ID和计价货币对应的账户总览
+	accountCacheMutex sync.Mutex
+)
+
+// AccountAsset is the balance and the value of an asset
+type AccountAsset struct {
+	Asset  string
+	Free   float64
+	Frozen float64
+	Price  float64 //以计价货币表示的价格,没有行情时为0
+	Value  float64
+}
+
+// ExchangeAccount is the account of an exchange
+type ExchangeAccount struct {
+	ID     int64
+	Name   string
+	Type   string
+	Total  float64
+	Assets []AccountAsset
+	Error  string //获取账户失败时的错误信息
+}
+
+// AccountOverview is the consolidated account of all the exchanges of a user
+type AccountOverview struct {
+	Quote     string
+	Total     float64
+	Exchanges []ExchangeAccount
+	Assets    []AccountAsset //所有交易所合计的资产,价格为合计估值/数量
+	Unpriced  []string       //没有行情无法估值的资产,不计入总估值
+	UpdatedAt time.Time
+}
+
+// CheckAccountQuote check if the quote currency is supported
+func CheckAccountQuote(quote string) error {
+	for _, q := range AccountQuotes {
+		if q == quote {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unsupported quote currency %v", quote)
+}
+
+// GetAccountOverview get the consolidated account of the user in the quote currency,
+// the result is cached for accountCacheTTL seconds unless refresh is true
+func GetAccountOverview(user model.User, quote string, refresh bool) (overview AccountOverview, err error) {
+	quote = strings.ToUpper(quote)
+	if err = CheckAccountQuote(quote); err != nil {
+		return
+	}
+	key := fmt.Sprint(user.ID, "/", quote)
+	ttl := time.Duration(config.Int("accountcachettl", accountDefaultCacheTTL)) * time.Second
+	accountCacheMutex.Lock()
+	overview, ok := accountCache[key]
+	accountCacheMutex.Unlock()
+	if ok && !refresh && time.Since(overview.UpdatedAt) < ttl {
+		return
+	}
+	if overview, err = newAccountOverview(user, quote); err != nil {
+		return
+	}
+	accountCacheMutex.Lock()
+	accountCache[key] = overview
+	accountCacheMutex.Unlock()
+	return
+}
+
+//newAccountOverview 获取用户所有交易所的账户并估值
+func newAccountOverview(user model.User, quote string) (overview AccountOverview, err error) {
+	exchanges, err := user.ListOwnExchange()
+	if err != nil {
+		return
+	}
+	overview = AccountOverview{
+		Quote:     quote,
+		Exchanges: make([]ExchangeAccount, len(exchanges)),
+		UpdatedAt: time.Now(),
+	}
+	//依次获取各交易所的账户,避免同时发出过多请求
+	for i, e := range exchanges {
+		overview.Exchanges[i] = getExchangeAccount(e, quote)
+	}
+	assets := make(map[string]*AccountAsset)
+	unpriced := make(map[string]bool)
+	for _, e := range overview.Exchanges {
+		overview.Total += e.Total
+		for _, a := range e.Assets {
+			total, ok := assets[a.Asset]
+			if !ok {
+				total = &AccountAsset{Asset: a.Asset}
+				assets[a.Asset] = total
+			}
+			total.Free += a.Free
+			total.Frozen += a.Frozen
+			total.Value += a.Value
+			if a.Price <= 0 {
+				unpriced[a.Asset] = true
+			}
+		}
+	}
+	for _, a := range assets {
+		if amount := a.Free + a.Frozen; amount > 0 {
+			a.Price = a.Value / amount
+		}
+		overview.Assets = append(overview.Assets, *a)
+	}
+	sort.Slice(overview.Assets, func(i, j int) bool {
+		return overview.Assets[i].Value > overview.Assets[j].Value
+	})
+	for a := range unpriced {
+		overview.Unpriced = append(overview.Unpriced, a)
+	}
+	sort.Strings(overview.Unpriced)
+	return
+}
+
+//getExchangeAccount 获取一个交易所的余额,冻结的资产以Frozen为前缀
+func getExchangeAccount(e model.Exchange, quote string) (account ExchangeAccount) {
+	account = ExchangeAccount{ID: e.ID, Name: e.Name, Type: e.Type}
+	errors := []string{}
+	exchange, err := NewExchange(api.Option{
+		Type:      e.Type,
+		Name:      e.Name,
+		AccessKey: e.AccessKey,
+		SecretKey: e.SecretKey,
+		LogHook: func(l model.Log) {
+			if l.Type != constant.ERROR {
+				return
+			}
+			errors = append(errors, l.Message)
+		},
+	})
+	if err != nil {
+		account.Error = fmt.Sprint(err)
+		return
+	}
+	balances, ok := exchange.GetAccount().(map[string]float64)
+	if !ok {
+		account.Error = strings.Join(errors, "; ")
+		if account.Error == "" {
+			account.Error = "GetAccount() failed"
+		}
+		return
+	}
+	assets := make(map[string]*AccountAsset)
+	for name, amount := range balances {
+		frozen := strings.HasPrefix(name, accountFrozenPrefix)
+		name = strings.ToUpper(strings.TrimPrefix(name, accountFrozenPrefix))
+		asset, ok := assets[name]
+		if !ok {
+			asset = &AccountAsset{Asset: name}
+			assets[name] = asset
+		}
+		if frozen {
+			asset.Frozen += amount
+		} else {
+			asset.Free += amount
+		}
+	}
+	prices := make(map[string]float64)
+	for _, a := range assets {
+		if a.Free+a.Frozen == 0 {
+			continue
+		}
+		a.Price = assetPrice(exchange, a.Asset, quote, prices)
+		a.Value = (a.Free + a.Frozen) * a.Price
+		account.Total += a.Value
+		account.Assets = append(account.Assets, *a)
+	}
+	sort.Slice(account.Assets, func(i, j int) bool {
+		return account.Assets[i].Value > account.Assets[j].Value
+	})
+	return
+}
+
+//tickerPrice 交易所支持该交易对时返回中间价,prices缓存一次估值中已经获取的行情
+func tickerPrice(e api.Exchange, stockType string, prices map[string]float64) float64 {
+	if price, ok := prices[stockType]; ok {
+		return price
+	}
+	price := 0.0
+	if e.GetMinAmount(stockType) > 0 {
+		if ticker, ok := e.GetTicker(stockType).(api.Ticker); ok {
+			if price = ticker.Mid; price <= 0 {
+				price = ticker.Buy
+			}
+		}
+	}
+	prices[stockType] = price
+	return price
+}
+
+//assetPrice 依次使用直接交易对、反向交易对和USDT交叉盘估值,无法估值时返回0
+func assetPrice(e api.Exchange, asset, quote string, prices map[string]float64) float64 {
+	if asset == quote {
+		return 1
+	}
+	if price := tickerPrice(e, asset+"/"+quote, prices); price > 0 {
+		return price
+	}
+	if price := tickerPrice(e, quote+"/"+asset, prices); price > 0 {
+		return 1 / price
+	}
+	if asset != "USDT" && quote != "USDT" {
+		a := tickerPrice(e, asset+"/USDT", prices)
+		q := tickerPrice(e, quote+"/USDT", prices)
+		if a > 0 && q > 0 {
+			return a / q
+		}
+	}
+	return 0
+}
+
+func init() {
+	go snapshotAccounts()
+}
+
+//snapshotAccounts 定期保存所有用户的账户总览,用于资产曲线
+func snapshotAccounts() {
+	for {
+		interval := config.Int("accountsnapshotinterval", accountDefaultSnapshotInterval)
+		if interval <= 0 {
+			time.Sleep(time.Minute)
+			continue
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+		quote := strings.ToUpper(config.String("accountsnapshotquote"))
+		if quote == "" {
+			quote = AccountQuotes[0]
+		}
+		users, err := model.ListAccountUser()
+		if err != nil {
+			log.Println("List account users error:", err)
+			continue
+		}
+		for _, user := range users {
+			overview, err := GetAccountOverview(user, quote, true)
+			if err != nil {
+				log.Printf("Get the account overview of %v error: %v\n", user.Username, err)
+				continue
+			}
+			detail, _ := json.Marshal(overview)
+			snapshot := model.AccountSnapshot{
+				UserID: user.ID,
+				Quote:  overview.Quote,
+				Total:  overview.Total,
+				Detail: string(detail),
+			}
+			if err := model.SaveAccountSnapshot(&snapshot); err != nil {
+				log.Printf("Save the account snapshot of %v error: %v\n", user.Username, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miaolz123/conver"
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+//消息总线的默认限制,配置文件中没有设置时使用
+const (
+	busDefaultQueueSize = 100  //每个订阅等待接收的最大消息数量,超出时丢弃最早的消息
+	busDefaultKeep      = 1000 //每个主题保留的持久化消息数量
+)
+
+//策略之间的消息总线,主题按照用户隔离,一个用户的策略不能接收其他用户的消息
+var bus = &messageBus{subscribers: make(map[string]map[int64]*subscription)}
+
+// Message is a message received by G.Receive
+type Message struct {
+	Topic    string
+	Data     interface{}
+	TraderID int64 //发布消息的策略
+	Time     int64 //发布的时间,单位毫秒
+	Dropped  int64 //在这条消息之前因为队列已满被丢弃的消息数量
+
+	id   int64  //持久化消息的ID,非持久化消息为0
+	data string //JSON格式的消息内容,每次接收时重新解析,订阅者之间互不影响
+}
+
+//一个策略对一个主题的订阅
+type subscription struct {
+	mutex     sync.Mutex
+	messages  []Message
+	dropped   int64
+	notify    chan struct{} //有新消息时通知等待中的Receive
+	replaying bool          //正在补发持久化的消息,期间发布的消息暂存在pending中
+	pending   []Message
+}
+
+//push 把消息放入队列,队列已满时丢弃最早的消息
+func (s *subscription) push(m Message, size int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.replaying {
+		s.pending = append(s.pending, m)
+		return
+	}
+	s.add(m, size)
+}
+
+//replay 先放入补发的消息,再放入补发期间发布的消息,已经补发过的持久化消息不再重复放入
+func (s *subscription) replay(messages []Message, size int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	last := int64(0)
+	for _, m := range messages {
+		s.add(m, size)
+		last = m.id
+	}
+	for _, m := range s.pending {
+		if m.id == 0 || m.id > last {
+			s.add(m, size)
+		}
+	}
+	s.pending, s.replaying = nil, false
+}
+
+func (s *subscription) add(m Message, size int) {
+	if len(s.messages) >= size {
+		drop := len(s.messages) - size + 1
+		s.messages = s.messages[drop:]
+		s.dropped += int64(drop)
+	}
+	s.messages = append(s.messages, m)
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+//pop 取出最早的消息
+func (s *subscription) pop() (m Message, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.messages) == 0 {
+		return
+	}
+	m, s.messages = s.messages[0], s.messages[1:]
+	m.Dropped, s.dropped = s.dropped, 0
+	return m, true
+}
+
+type messageBus struct {
+	mutex       sync.RWMutex
+	subscribers map[string]map[int64]*subscription //用户命名空间中的主题 -> 策略ID -> 订阅
+}
+
+//busKey 用户命名空间中的主题
+func busKey(userID int64, topic string) string {
+	return fmt.Sprintf("%v/%v", userID, topic)
+}
+
+func (b *messageBus) subscribe(userID, traderID int64, topic string) (s *subscription, created bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	key := busKey(userID, topic)
+	if _, ok := b.subscribers[key]; !ok {
+		b.subscribers[key] = make(map[int64]*subscription)
+	}
+	if s, ok := b.subscribers[key][traderID]; ok {
+		return s, false
+	}
+	//新的订阅先暂存发布的消息,补发持久化的消息之后再放入队列
+	s = &subscription{notify: make(chan struct{}, 1), replaying: true}
+	b.subscribers[key][traderID] = s
+	return s, true
+}
+
+func (b *messageBus) subscription(userID, traderID int64, topic string) *subscription {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.subscribers[busKey(userID, topic)][traderID]
+}
+
+//unsubscribeAll 策略停止时取消它的所有订阅
+func (b *messageBus) unsubscribeAll(traderID int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for key, subscribers := range b.subscribers {
+		delete(subscribers, traderID)
+		if len(subscribers) == 0 {
+			delete(b.subscribers, key)
+		}
+	}
+}
+
+func (b *messageBus) publish(userID int64, m Message) {
+	size := config.Int("busqueuesize", busDefaultQueueSize)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, s := range b.subscribers[busKey(userID, m.Topic)] {
+		s.push(m, size)
+	}
+}
+
+// Publish publish a message to the topic, the message is saved for the subscribers to catch up when persist is true
+func (g *Global) Publish(topic string, data interface{}, persist ...bool) bool {
+	g.heartbeat.beat()
+	if topic == "" {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Publish(), Invalid topic")
+		return false
+	}
+	bs, err := json.Marshal(data)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Publish(), ", err)
+		return false
+	}
+	m := Message{Topic: topic, TraderID: g.ID, Time: time.Now().UnixNano() / int64(time.Millisecond), data: string(bs)}
+	if len(persist) > 0 && persist[0] {
+		message := model.BusMessage{UserID: g.UserID, Topic: topic, TraderID: g.ID, Data: m.data}
+		if err := model.SaveBusMessage(&message, int64(config.Int("buskeep", busDefaultKeep))); err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Publish(), ", err)
+			return false
+		}
+		m.id = message.ID
+	}
+	bus.publish(g.UserID, m)
+	return true
+}
+
+// Subscribe subscribe the topic, the persisted messages since the last received one are queued again
+func (g *Global) Subscribe(topic string) bool {
+	g.heartbeat.beat()
+	if topic == "" {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Subscribe(), Invalid topic")
+		return false
+	}
+	s, created := bus.subscribe(g.UserID, g.ID, topic)
+	if !created {
+		return true
+	}
+	size := config.Int("busqueuesize", busDefaultQueueSize)
+	replayed := []Message{}
+	defer func() {
+		s.replay(replayed, size)
+	}()
+	cursor, ok, err := model.GetBusCursor(g.ID, topic)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Subscribe(), ", err)
+		return false
+	}
+	if !ok {
+		//第一次订阅时从最新的消息开始
+		cursor = model.BusCursor{TraderID: g.ID, Topic: topic}
+		if cursor.MessageID, err = model.LastBusMessageID(g.UserID, topic); err == nil {
+			err = model.SaveBusCursor(&cursor)
+		}
+		if err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Subscribe(), ", err)
+			return false
+		}
+		return true
+	}
+	messages, err := model.ListBusMessage(g.UserID, topic, cursor.MessageID, int64(size))
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Subscribe(), ", err)
+		return false
+	}
+	for _, m := range messages {
+		replayed = append(replayed, Message{Topic: topic, TraderID: m.TraderID, Time: m.CreatedAt.UnixNano() / int64(time.Millisecond), id: m.ID, data: m.Data})
+	}
+	return true
+}
+
+// Receive receive a message of the subscribed topic, wait at most timeout milliseconds, return null when timeout
+func (g *Global) Receive(topic string, timeouts ...interface{}) interface{} {
+	s := bus.subscription(g.UserID, g.ID, topic)
+	if s == nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Receive(), the topic ", topic, " is not subscribed")
+		return nil
+	}
+	timeout := int64(0)
+	if len(timeouts) > 0 {
+		timeout = conver.Int64Must(timeouts[0])
+	}
+	m, ok := s.pop()
+	if !ok && timeout > 0 {
+		//等待消息期间和Sleep一样执行到期的定时器
+		g.idle(func() {
+			deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+			for {
+				g.runTimers()
+				if m, ok = s.pop(); ok {
+					return
+				}
+				now := time.Now()
+				if !now.Before(deadline) {
+					return
+				}
+				wake := deadline
+				if next, found := g.scheduler.next(); found && next.Before(wake) && !g.scheduler.firing && !g.tasksRunning() {
+					wake = next
+				}
+				select {
+				case <-s.notify:
+				case <-time.After(wake.Sub(now)):
+				}
+			}
+		})
+	}
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(m.data), &m.Data); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Receive(), ", err)
+		return nil
+	}
+	if m.id > 0 {
+		cursor, _, err := model.GetBusCursor(g.ID, topic)
+		if err == nil && cursor.MessageID < m.id {
+			cursor.TraderID, cursor.Topic, cursor.MessageID = g.ID, topic, m.id
+			err = model.SaveBusCursor(&cursor)
+		}
+		if err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Receive(), ", err)
+		}
+	}
+	return m
+}
@@ -0,0 +1,76 @@
+package trader
+
+import (
+	"time"
+
+	"github.com/miaolz123/conver"
+	"github.com/mitchellh/mapstructure"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+)
+
+// ChartLayout declare the title and the series of a chart, layout: {Title, Series: [{Name, Type, Overlay, Color}]}
+func (g *Global) ChartLayout(name string, layout interface{}) bool {
+	g.heartbeat.beat()
+	l := model.ChartLayout{}
+	if err := mapstructure.WeakDecode(layout, &l); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ChartLayout(), Invalid layout: ", err)
+		return false
+	}
+	for _, s := range l.Series {
+		if !chartType(s.Type) {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ChartLayout(), Invalid series type ", s.Type)
+			return false
+		}
+	}
+	l.TraderID = g.ID
+	l.Name = name
+	if l.Title == "" {
+		l.Title = name
+	}
+	if err := model.SaveChartLayout(l); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ChartLayout(), ", err)
+		return false
+	}
+	return true
+}
+
+//chartType 系列的类型为空时使用折线
+func chartType(typ string) bool {
+	if typ == "" {
+		return true
+	}
+	for _, t := range constant.ChartTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Chart add a point to a series of a chart, x is the timestamp in milliseconds by default,
+// y is a number or a candlestick {Open, High, Low, Close} / [Open, High, Low, Close]
+func (g *Global) Chart(name, series string, x, y interface{}) bool {
+	g.heartbeat.beat()
+	p := model.ChartPoint{TraderID: g.ID, Chart: name, Series: series, X: conver.Int64Must(x)}
+	if p.X == 0 {
+		p.X = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	switch v := y.(type) {
+	case map[string]interface{}:
+		p.Open = conver.Float64Must(v["Open"])
+		p.High = conver.Float64Must(v["High"])
+		p.Low = conver.Float64Must(v["Low"])
+		p.Y = conver.Float64Must(v["Close"])
+	case []interface{}:
+		if len(v) != 4 {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Chart(), a candlestick needs 4 values")
+			return false
+		}
+		p.Open, p.High, p.Low, p.Y = conver.Float64Must(v[0]), conver.Float64Must(v[1]), conver.Float64Must(v[2]), conver.Float64Must(v[3])
+	default:
+		p.Y = conver.Float64Must(y)
+	}
+	model.SaveChartPoint(p)
+	return true
+}
@@ -0,0 +1,338 @@
+package trader
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	gast "github.com/dop251/goja/ast"
+	gfile "github.com/dop251/goja/file"
+	gparser "github.com/dop251/goja/parser"
+	"github.com/phonegapX/QuantBot/api"
+	"github.com/robertkrimen/otto/ast"
+	"github.com/robertkrimen/otto/file"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// Diagnostic Severity
+const (
+	DiagnosticError   = "error"
+	DiagnosticWarning = "warning"
+)
+
+// Check Variable
+var (
+	exchangeNames = methodNames( //脚本中E/Exchange对象可以调用的方法,包括各个交易所特有的方法
+		reflect.TypeOf((*api.Exchange)(nil)).Elem(),
+		reflect.TypeOf(&api.Zb{}),
+		reflect.TypeOf(&api.OKEX{}),
+		reflect.TypeOf(&api.Huobi{}),
+		reflect.TypeOf(&api.Binance{}),
+		reflect.TypeOf(&api.GateIo{}),
+		reflect.TypeOf(&api.Poloniex{}),
+		reflect.TypeOf(&api.OkexFuture{}),
+		reflect.TypeOf(&api.BigOne{}),
+	)
+	globalNames   = methodNames(reflect.TypeOf(&Global{})) //脚本中G/Global对象可以访问的方法和字段
+	handlerMethod = map[string]int{                        //以函数名作为参数的方法,值为函数名参数的位置
+		"SetInterval": 2, "SetTimeout": 2, "Cron": 2, "AddTask": 1, "BindTaskParam": 1,
+	}
+)
+
+// Diagnostic is a problem found in an algorithm script
+type Diagnostic struct {
+	Severity string
+	Line     int
+	Column   int
+	Message  string
+}
+
+//methodNames 返回类型的所有导出方法名,结构体还包括导出的字段名
+func methodNames(types ...reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for _, t := range types {
+		for i := 0; i < t.NumMethod(); i++ {
+			names[t.Method(i).Name] = true
+		}
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				for name := range methodNames(f.Type) {
+					names[name] = true
+				}
+			} else if f.PkgPath == "" {
+				names[f.Name] = true
+			}
+		}
+	}
+	return names
+}
+
+//脚本中引用的符号,由各个脚本引擎的解析器生成
+type scriptSymbols struct {
+	functions map[string]bool //顶层声明的函数和变量
+	members   []symbolRef     //E/G对象的成员
+	handlers  []symbolRef     //定时器和任务方法中作为参数的函数名
+}
+
+type symbolRef struct {
+	object string
+	name   string
+	line   int
+	column int
+}
+
+// CheckScript compile the script with the parser of the engine and check the main function and the calls of E/G methods
+func CheckScript(script, engine string) (diagnostics []Diagnostic) {
+	return checkScript(script, engine, true)
+}
+
+// CheckLibrary compile the library script, a library does not need the main function
+//
+// libraries can be required by the algorithms of any engine, so they are checked as ES5
+func CheckLibrary(script string) (diagnostics []Diagnostic) {
+	return checkScript(script, EngineOtto, false)
+}
+
+func checkScript(script, engine string, requireMain bool) (diagnostics []Diagnostic) {
+	parse := parseOtto
+	if engineName(engine) == EngineGoja {
+		parse = parseGoja
+	}
+	symbols, diagnostics := parse(script)
+	if len(diagnostics) > 0 {
+		return
+	}
+	if requireMain && !symbols.functions["main"] {
+		diagnostics = append(diagnostics, Diagnostic{DiagnosticError, 1, 1, "Can not found the main function"})
+	}
+	for _, m := range symbols.members {
+		names := globalNames
+		if m.object == "E" || m.object == "Exchange" {
+			names = exchangeNames
+		}
+		if !names[m.name] {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, m.line, m.column, fmt.Sprintf("Unknown method %v.%v", m.object, m.name)})
+		}
+	}
+	//定时器和任务的函数名需要是顶层声明的函数
+	for _, h := range symbols.handlers {
+		if !symbols.functions[h.name] {
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticWarning, h.line, h.column, fmt.Sprintf("%v(), can not found the function %v", h.object, h.name)})
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	return
+}
+
+//isGlobalObject 脚本中的全局对象名,返回false表示不需要检查它的成员
+func isGlobalObject(name string) bool {
+	switch name {
+	case "E", "Exchange", "G", "Global":
+		return true
+	}
+	return false
+}
+
+//parseOtto 使用otto的解析器解析ES5脚本
+func parseOtto(script string) (symbols scriptSymbols, diagnostics []Diagnostic) {
+	program, err := parser.ParseFile(nil, "", script, 0)
+	if err != nil {
+		switch err := err.(type) {
+		case parser.ErrorList:
+			for _, e := range err {
+				d := Diagnostic{DiagnosticError, e.Position.Line, e.Position.Column, e.Message}
+				if len(diagnostics) == 0 || diagnostics[len(diagnostics)-1] != d {
+					diagnostics = append(diagnostics, d)
+				}
+			}
+		case *parser.Error:
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, err.Position.Line, err.Position.Column, err.Message})
+		default:
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, 0, 0, fmt.Sprint(err)})
+		}
+		return
+	}
+	position := func(idx file.Idx) (line, column int) {
+		if p := program.File.Position(idx); p != nil {
+			return p.Line, p.Column
+		}
+		return
+	}
+	symbols.functions = make(map[string]bool)
+	for _, d := range program.DeclarationList {
+		switch d := d.(type) {
+		case *ast.FunctionDeclaration:
+			if d.Function.Name != nil {
+				symbols.functions[d.Function.Name.Name] = true
+			}
+		case *ast.VariableDeclaration:
+			for _, v := range d.List {
+				symbols.functions[v.Name] = true
+			}
+		}
+	}
+	walkNode(reflect.ValueOf(program), make(map[interface{}]bool), func(node interface{}) {
+		switch node := node.(type) {
+		case *ast.DotExpression:
+			object, ok := node.Left.(*ast.Identifier)
+			if !ok || node.Identifier == nil || !isGlobalObject(object.Name) {
+				return
+			}
+			line, column := position(node.Identifier.Idx)
+			symbols.members = append(symbols.members, symbolRef{object.Name, node.Identifier.Name, line, column})
+		case *ast.CallExpression:
+			dot, ok := node.Callee.(*ast.DotExpression)
+			if !ok || dot.Identifier == nil {
+				return
+			}
+			if object, ok := dot.Left.(*ast.Identifier); !ok || (object.Name != "G" && object.Name != "Global") {
+				return
+			}
+			i, ok := handlerMethod[dot.Identifier.Name]
+			if !ok || i >= len(node.ArgumentList) {
+				return
+			}
+			if fn, ok := node.ArgumentList[i].(*ast.StringLiteral); ok {
+				line, column := position(fn.Idx)
+				symbols.handlers = append(symbols.handlers, symbolRef{dot.Identifier.Name, fn.Value, line, column})
+			}
+		}
+	})
+	return
+}
+
+//parseGoja 使用goja的解析器解析ES2015+脚本,顶层的let、const和class同样可以作为函数名
+func parseGoja(script string) (symbols scriptSymbols, diagnostics []Diagnostic) {
+	program, err := gparser.ParseFile(nil, "", script, 0)
+	if err != nil {
+		switch err := err.(type) {
+		case gparser.ErrorList:
+			for _, e := range err {
+				d := Diagnostic{DiagnosticError, e.Position.Line, e.Position.Column, e.Message}
+				if len(diagnostics) == 0 || diagnostics[len(diagnostics)-1] != d {
+					diagnostics = append(diagnostics, d)
+				}
+			}
+		case *gparser.Error:
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, err.Position.Line, err.Position.Column, err.Message})
+		default:
+			diagnostics = append(diagnostics, Diagnostic{DiagnosticError, 0, 0, fmt.Sprint(err)})
+		}
+		return
+	}
+	position := func(idx gfile.Idx) (line, column int) {
+		p := program.File.Position(int(idx) - program.File.Base())
+		return p.Line, p.Column
+	}
+	symbols.functions = make(map[string]bool)
+	bind := func(list []*gast.Binding) {
+		for _, b := range list {
+			if id, ok := b.Target.(*gast.Identifier); ok {
+				symbols.functions[id.Name.String()] = true
+			}
+		}
+	}
+	for _, s := range program.Body {
+		switch s := s.(type) {
+		case *gast.FunctionDeclaration:
+			if s.Function.Name != nil {
+				symbols.functions[s.Function.Name.Name.String()] = true
+			}
+		case *gast.ClassDeclaration:
+			if s.Class.Name != nil {
+				symbols.functions[s.Class.Name.Name.String()] = true
+			}
+		case *gast.VariableStatement:
+			bind(s.List)
+		case *gast.LexicalDeclaration:
+			bind(s.List)
+		}
+	}
+	walkNode(reflect.ValueOf(program), make(map[interface{}]bool), func(node interface{}) {
+		switch node := node.(type) {
+		case *gast.DotExpression:
+			object, ok := node.Left.(*gast.Identifier)
+			if !ok || !isGlobalObject(object.Name.String()) {
+				return
+			}
+			line, column := position(node.Identifier.Idx)
+			symbols.members = append(symbols.members, symbolRef{object.Name.String(), node.Identifier.Name.String(), line, column})
+		case *gast.CallExpression:
+			dot, ok := node.Callee.(*gast.DotExpression)
+			if !ok {
+				return
+			}
+			if object, ok := dot.Left.(*gast.Identifier); !ok || (object.Name != "G" && object.Name != "Global") {
+				return
+			}
+			i, ok := handlerMethod[dot.Identifier.Name.String()]
+			if !ok || i >= len(node.ArgumentList) {
+				return
+			}
+			if fn, ok := node.ArgumentList[i].(*gast.StringLiteral); ok {
+				line, column := position(fn.Idx)
+				symbols.handlers = append(symbols.handlers, symbolRef{dot.Identifier.Name.String(), fn.Value.String(), line, column})
+			}
+		}
+	})
+	return
+}
+
+// HasError check if there is any error in the diagnostics
+func HasError(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == DiagnosticError {
+			return true
+		}
+	}
+	return false
+}
+
+//walkNode 遍历语法树的所有节点,函数声明同时出现在语句和声明列表中,每个节点只访问一次
+//
+//只进入和根节点同一个包中的类型,otto和goja的语法树都可以使用
+func walkNode(value reflect.Value, visited map[interface{}]bool, visit func(interface{})) {
+	walkPackage(value, value.Elem().Type().PkgPath(), visited, visit)
+}
+
+func walkPackage(value reflect.Value, pkg string, visited map[interface{}]bool, visit func(interface{})) {
+	switch value.Kind() {
+	case reflect.Interface:
+		if !value.IsNil() {
+			walkPackage(value.Elem(), pkg, visited, visit)
+		}
+	case reflect.Ptr:
+		if value.IsNil() || value.Elem().Type().PkgPath() != pkg {
+			return
+		}
+		node := value.Interface()
+		if visited[node] {
+			return
+		}
+		visited[node] = true
+		visit(node)
+		walkPackage(value.Elem(), pkg, visited, visit)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				walkPackage(value.Field(i), pkg, visited, visit)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			walkPackage(value.Index(i), pkg, visited, visit)
+		}
+	}
+}
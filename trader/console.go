@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//控制台表达式等待执行的最长时间,otto在脚本休眠期间不会执行中断函数,goja只在Sleep中执行
+const evalTimeout = 30 * time.Second
+
+//控制台表达式的执行结果
+type evalResult struct {
+	value string
+	err   error
+}
+
+// Eval evaluate a js expression in the context of a running trader and return the result as JSON
+func Eval(id int64, expression string) (result string, err error) {
+	executorMutex.Lock()
+	t, ok := Executor[id]
+	executorMutex.Unlock()
+	if !ok || t == nil || t.Status <= 0 {
+		return "", fmt.Errorf("The Trader is not running")
+	}
+	t.evalMutex.Lock()
+	defer t.evalMutex.Unlock()
+	done := make(chan evalResult, 1)
+	//表达式在主js虚拟机的goroutine中执行,和主循环串行,超时后取消,不会在之后再执行
+	fn := newPosted(func() {
+		result, halt := t.eval(expression)
+		done <- result
+		if halt != nil {
+			panic(halt)
+		}
+	})
+	if !t.vm.post(fn, evalTimeout) {
+		return "", fmt.Errorf("The Trader is busy")
+	}
+	timer := time.NewTimer(evalTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		fn.cancel()
+		return "", fmt.Errorf("Evaluation timeout, the Trader may be sleeping")
+	}
+}
+
+//eval 在当前作用域中执行表达式,表达式中的错误不会结束策略,执行期间收到的停止中断通过halt返回
+func (g *Global) eval(expression string) (result evalResult, halt interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			result.err = fmt.Errorf("%v", err)
+			switch err {
+			case errHalt, errHung, errCPULimit, errMemoryLimit:
+				halt = err
+			}
+		}
+	}()
+	v, err := g.vm.eval(expression)
+	if err != nil {
+		result.err = err
+		return
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		bs, _ = json.Marshal(fmt.Sprint(v))
+	}
+	result.value = string(bs)
+	return
+}
@@ -0,0 +1,85 @@
+package trader
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// script engines
+const (
+	EngineOtto = "otto" //ES5,默认的脚本引擎
+	EngineGoja = "goja" //ES2015+,支持let、箭头函数、class、Promise和模板字符串
+)
+
+//所有脚本引擎的构造函数
+var engineMaker = map[string]func() engine{
+	EngineOtto: newOttoEngine,
+	EngineGoja: newGojaEngine,
+}
+
+//脚本引擎,策略的主循环、任务、定时器、控制台、模块库和资源限制都通过它访问js虚拟机
+//
+//所有的中断(停止、超时、资源超限、看门狗)都以panic的形式从run/call中抛出,和otto的中断通道保持一致
+type engine interface {
+	set(name string, value interface{}) error                   //设置全局变量
+	run(filename, script string) error                          //执行脚本
+	has(name string) bool                                       //全局函数是否存在
+	call(name string, args ...interface{}) (interface{}, error) //调用全局函数,返回值转换为Go的数据
+	eval(expression string) (interface{}, error)                //在当前作用域中执行表达式,函数转换为源码
+	module(filename, script string) (interface{}, error)        //按照CommonJS的方式执行模块,返回module.exports
+	object(fields map[string]interface{}) (interface{}, error)  //创建js对象
+	array(items []interface{}) (interface{}, error)             //创建js数组
+	throw(name, message string)                                 //在Go函数中抛出js异常
+	interrupt(err error)                                        //非阻塞地中断脚本,已有中断时不再重复发送
+	clearInterrupt()                                            //丢弃遗留的中断
+	post(fn *posted, timeout time.Duration) bool                //在虚拟机的goroutine中执行fn
+	poll()                                                      //执行post的函数,只在主循环的Sleep中调用
+	countObjects(skip map[string]bool, max int64) bool          //全局可达的对象数量是否超过max
+	fork(setup func(engine) error) (engine, error)              //创建任务使用的虚拟机,setup用来绑定全局对象
+}
+
+//posted 等待在虚拟机的goroutine中执行的函数,调用方放弃等待时取消,取消后不再执行
+type posted struct {
+	fn       func()
+	canceled int32
+}
+
+func newPosted(fn func()) *posted {
+	return &posted{fn: fn}
+}
+
+//run 在虚拟机的goroutine中执行,已取消的函数直接跳过
+func (p *posted) run() {
+	if atomic.LoadInt32(&p.canceled) == 0 {
+		p.fn()
+	}
+}
+
+func (p *posted) cancel() {
+	atomic.StoreInt32(&p.canceled, 1)
+}
+
+// CheckEngine check if the script engine is supported
+func CheckEngine(name string) error {
+	if _, ok := engineMaker[engineName(name)]; !ok {
+		return fmt.Errorf("Unsupported script engine %v", name)
+	}
+	return nil
+}
+
+//engineName 策略没有设置脚本引擎时使用otto
+func engineName(name string) string {
+	if name == "" {
+		return EngineOtto
+	}
+	return name
+}
+
+func newEngine(name string) (engine, error) {
+	maker, ok := engineMaker[engineName(name)]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported script engine %v", name)
+	}
+	return maker(), nil
+}
@@ -0,0 +1,217 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+)
+
+//goja脚本引擎,支持ES2015+,创建虚拟机时执行插件脚本
+type gojaEngine struct {
+	vm    *goja.Runtime
+	queue chan *posted //等待在Sleep中执行的函数,goja的中断会结束脚本,不能用来执行函数
+}
+
+func newGojaEngine() engine {
+	e := &gojaEngine{
+		vm:    goja.New(),
+		queue: make(chan *posted, 1),
+	}
+	if _, err := e.vm.RunScript("plugin.js", pluginScript()); err != nil {
+		log.Println("Load plugins error:", err)
+	}
+	return e
+}
+
+//check 把goja的中断错误转换为panic,和otto的中断通道保持一致
+func (e *gojaEngine) check(err error) error {
+	if err, ok := err.(*goja.InterruptedError); ok {
+		panic(err.Value())
+	}
+	return err
+}
+
+func (e *gojaEngine) set(name string, value interface{}) error {
+	return e.vm.Set(name, value)
+}
+
+//compile goja执行脚本时会把语法错误转换为没有位置的js异常,先解析脚本以保留错误的行号和列号
+func (e *gojaEngine) compile(filename, script string) (*goja.Program, error) {
+	program, err := parser.ParseFile(nil, filename, script, 0)
+	if err != nil {
+		return nil, err
+	}
+	return goja.CompileAST(program, false)
+}
+
+func (e *gojaEngine) run(filename, script string) error {
+	program, err := e.compile(filename, script)
+	if err != nil {
+		return err
+	}
+	_, err = e.vm.RunProgram(program)
+	return e.check(err)
+}
+
+func (e *gojaEngine) has(name string) bool {
+	_, ok := goja.AssertFunction(e.vm.Get(name))
+	return ok
+}
+
+func (e *gojaEngine) call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := goja.AssertFunction(e.vm.Get(name))
+	if !ok {
+		return nil, fmt.Errorf("%v is not a function", name)
+	}
+	values := make([]goja.Value, len(args))
+	for i, arg := range args {
+		values[i] = e.vm.ToValue(arg)
+	}
+	value, err := fn(goja.Undefined(), values...)
+	if err = e.check(err); err != nil {
+		return nil, err
+	}
+	return value.Export(), nil
+}
+
+func (e *gojaEngine) eval(expression string) (interface{}, error) {
+	value, err := e.vm.RunString(expression)
+	if err = e.check(err); err != nil {
+		return nil, err
+	}
+	if _, ok := goja.AssertFunction(value); ok {
+		return value.String(), nil
+	}
+	return value.Export(), nil
+}
+
+func (e *gojaEngine) module(filename, script string) (interface{}, error) {
+	//包装代码不占用行,错误的行号和模块源码一致
+	program, err := e.compile(filename, "(function(module, exports) {"+script+"\n})")
+	if err != nil {
+		return nil, err
+	}
+	value, err := e.vm.RunProgram(program)
+	if err = e.check(err); err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("Invalid module %v", filename)
+	}
+	module := e.vm.NewObject()
+	exports := e.vm.NewObject()
+	if err := module.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	if _, err = fn(goja.Undefined(), module, exports); e.check(err) != nil {
+		//和otto一致,只保留异常本身,不包含堆栈
+		if ex, ok := err.(*goja.Exception); ok {
+			return nil, fmt.Errorf("%v", ex.Value())
+		}
+		return nil, err
+	}
+	return module.Get("exports"), nil
+}
+
+func (e *gojaEngine) object(fields map[string]interface{}) (interface{}, error) {
+	object := e.vm.NewObject()
+	for name, value := range fields {
+		if err := object.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
+
+func (e *gojaEngine) array(items []interface{}) (interface{}, error) {
+	return e.vm.NewArray(items...), nil
+}
+
+func (e *gojaEngine) throw(name, message string) {
+	object, err := e.vm.New(e.vm.Get("Error"), e.vm.ToValue(message))
+	if err != nil {
+		panic(e.vm.NewGoError(fmt.Errorf("%v: %v", name, message)))
+	}
+	object.Set("name", name)
+	panic(object)
+}
+
+func (e *gojaEngine) interrupt(err error) {
+	e.vm.Interrupt(err)
+}
+
+func (e *gojaEngine) clearInterrupt() {
+	e.vm.ClearInterrupt()
+}
+
+//post 把fn放入队列,在主循环下一次Sleep时执行
+func (e *gojaEngine) post(fn *posted, timeout time.Duration) bool {
+	select {
+	case e.queue <- fn:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (e *gojaEngine) poll() {
+	for {
+		select {
+		case fn := <-e.queue:
+			fn.run()
+		default:
+			return
+		}
+	}
+}
+
+//countObjects 统计全局对象上可达的对象数量,顶层的let和const声明不在全局对象上,不会被统计
+func (e *gojaEngine) countObjects(skip map[string]bool, max int64) bool {
+	global := e.vm.GlobalObject()
+	visited := make(map[*goja.Object]bool)
+	count := int64(0)
+	for _, key := range global.Keys() {
+		if skip[key] {
+			continue
+		}
+		if countGojaObjects(global.Get(key), visited, &count, max) {
+			return true
+		}
+	}
+	return false
+}
+
+//countGojaObjects 递归统计可达的对象数量,超过max时返回true
+func countGojaObjects(value goja.Value, visited map[*goja.Object]bool, count *int64, max int64) bool {
+	object, ok := value.(*goja.Object)
+	if !ok || visited[object] {
+		return false
+	}
+	visited[object] = true
+	*count++
+	if *count > max {
+		return true
+	}
+	if _, ok := goja.AssertFunction(object); ok {
+		return false
+	}
+	for _, key := range object.Keys() {
+		if countGojaObjects(object.Get(key), visited, count, max) {
+			return true
+		}
+	}
+	return false
+}
+
+//fork goja不能复制虚拟机,任务使用新的虚拟机,重新绑定全局对象并执行策略脚本
+func (e *gojaEngine) fork(setup func(engine) error) (engine, error) {
+	vm := newGojaEngine()
+	if err := setup(vm); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
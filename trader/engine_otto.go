@@ -0,0 +1,266 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/robertkrimen/otto"
+)
+
+//otto.Object没有公开的标识,统计对象数量时用它的第一个字段(指向内部对象的指针)判断是否已经统计过,
+//otto升级后这个字段不再是指针时关闭otto策略的对象数量限制,不会panic
+var ottoObjectIdentity = func() bool {
+	t := reflect.TypeOf(otto.Object{})
+	if t.NumField() == 0 || t.Field(0).Type.Kind() != reflect.Ptr {
+		log.Println("Unsupported otto.Object layout, the object limit of otto traders is disabled")
+		return false
+	}
+	return true
+}()
+
+//otto脚本引擎,插件通过otto的registry在创建虚拟机时加载
+//
+//中断通道中只放入dispatch,停止中断和控制台的函数分别保存,通道已满时不会丢失停止中断
+type ottoEngine struct {
+	ctx   *otto.Otto
+	mutex sync.Mutex
+	halt  interface{}  //等待处理的停止中断
+	queue chan *posted //等待执行的控制台函数
+}
+
+func newOttoEngine() engine {
+	return newOttoEngineWith(otto.New())
+}
+
+func newOttoEngineWith(ctx *otto.Otto) *ottoEngine {
+	ctx.Interrupt = make(chan func(), 1)
+	return &ottoEngine{ctx: ctx, queue: make(chan *posted, 1)}
+}
+
+//notify 通知虚拟机调用dispatch,通道中已有dispatch时它会处理本次的中断或函数
+func (e *ottoEngine) notify() {
+	select {
+	case e.ctx.Interrupt <- e.dispatch:
+	default:
+	}
+}
+
+//dispatch 在js虚拟机的goroutine中执行,先处理停止中断,再执行控制台的函数
+func (e *ottoEngine) dispatch() {
+	e.mutex.Lock()
+	halt := e.halt
+	e.halt = nil
+	e.mutex.Unlock()
+	if halt != nil {
+		panic(halt)
+	}
+	for {
+		select {
+		case fn := <-e.queue:
+			fn.run()
+		default:
+			return
+		}
+	}
+}
+
+func (e *ottoEngine) set(name string, value interface{}) error {
+	return e.ctx.Set(name, value)
+}
+
+func (e *ottoEngine) run(filename, script string) error {
+	s, err := e.ctx.Compile(filename, script)
+	if err != nil {
+		return err
+	}
+	_, err = e.ctx.Run(s)
+	return err
+}
+
+func (e *ottoEngine) has(name string) bool {
+	fn, err := e.ctx.Get(name)
+	return err == nil && fn.IsFunction()
+}
+
+func (e *ottoEngine) call(name string, args ...interface{}) (interface{}, error) {
+	fn, err := e.ctx.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fn.IsFunction() {
+		return nil, fmt.Errorf("%v is not a function", name)
+	}
+	value, err := fn.Call(fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	return value.Export()
+}
+
+func (e *ottoEngine) eval(expression string) (interface{}, error) {
+	value, err := e.ctx.Eval(expression)
+	if err != nil {
+		return nil, err
+	}
+	if value.IsFunction() {
+		return value.String(), nil
+	}
+	return value.Export()
+}
+
+func (e *ottoEngine) module(filename, script string) (interface{}, error) {
+	//包装代码不占用行,错误的行号和模块源码一致
+	s, err := e.ctx.Compile(filename, "(function(module, exports) {"+script+"\n})")
+	if err != nil {
+		return nil, err
+	}
+	fn, err := e.ctx.Run(s)
+	if err != nil {
+		return nil, err
+	}
+	module, err := e.ctx.Object(`({exports: {}})`)
+	if err != nil {
+		return nil, err
+	}
+	exports, err := module.Get("exports")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = fn.Call(otto.UndefinedValue(), module, exports); err != nil {
+		return nil, err
+	}
+	return module.Get("exports")
+}
+
+func (e *ottoEngine) object(fields map[string]interface{}) (interface{}, error) {
+	object, err := e.ctx.Object(`({})`)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if err := object.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
+
+func (e *ottoEngine) array(items []interface{}) (interface{}, error) {
+	array, err := e.ctx.Object(`([])`)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if _, err := array.Call("push", item); err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+func (e *ottoEngine) throw(name, message string) {
+	panic(e.ctx.MakeCustomError(name, message))
+}
+
+func (e *ottoEngine) interrupt(err error) {
+	//已有的停止中断同样会结束脚本,不需要覆盖
+	e.mutex.Lock()
+	if e.halt == nil {
+		e.halt = err
+	}
+	e.mutex.Unlock()
+	e.notify()
+}
+
+func (e *ottoEngine) clearInterrupt() {
+	e.mutex.Lock()
+	e.halt = nil
+	e.mutex.Unlock()
+	for {
+		select {
+		case <-e.ctx.Interrupt:
+		case <-e.queue:
+		default:
+			return
+		}
+	}
+}
+
+//post 把fn放入队列并通过中断通道通知,js虚拟机在执行下一条语句之前处理
+func (e *ottoEngine) post(fn *posted, timeout time.Duration) bool {
+	select {
+	case e.queue <- fn:
+		e.notify()
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (e *ottoEngine) poll() {}
+
+func (e *ottoEngine) countObjects(skip map[string]bool, max int64) (exceeded bool) {
+	if !ottoObjectIdentity {
+		return false
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			//统计期间收到的停止中断继续抛出
+			switch err {
+			case errHalt, errHung, errCPULimit, errMemoryLimit:
+				panic(err)
+			}
+			log.Println("Count the objects of otto error:", err)
+			exceeded = false
+		}
+	}()
+	global, err := e.ctx.Object("this")
+	if err != nil {
+		return false
+	}
+	visited := make(map[uintptr]bool)
+	count := int64(0)
+	for _, key := range global.Keys() {
+		if skip[key] {
+			continue
+		}
+		if value, err := global.Get(key); err == nil && countOttoObjects(value, visited, &count, max) {
+			return true
+		}
+	}
+	return false
+}
+
+//countOttoObjects 递归统计可达的对象数量,超过max时返回true
+func countOttoObjects(value otto.Value, visited map[uintptr]bool, count *int64, max int64) bool {
+	if !value.IsObject() {
+		return false
+	}
+	object := value.Object()
+	id := reflect.ValueOf(object).Elem().Field(0).Pointer()
+	if visited[id] {
+		return false
+	}
+	visited[id] = true
+	*count++
+	if *count > max {
+		return true
+	}
+	if object.Class() == "Function" {
+		return false
+	}
+	for _, key := range object.Keys() {
+		if v, err := object.Get(key); err == nil && countOttoObjects(v, visited, count, max) {
+			return true
+		}
+	}
+	return false
+}
+
+//fork 复制当前的虚拟机,全局对象和已经执行的脚本都会被复制,不需要再次绑定
+func (e *ottoEngine) fork(setup func(engine) error) (engine, error) {
+	return newOttoEngineWith(e.ctx.Copy()), nil
+}
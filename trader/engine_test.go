@@ -0,0 +1,38 @@
+package trader
+
+import "testing"
+
+//性能测试脚本,只使用ES5语法,所有脚本引擎都可以执行
+const benchmarkScript = `
+var sum = 0;
+for (var i = 0; i < 200000; i++) {
+	sum += Math.sqrt(i) * Math.sin(i);
+}
+var records = [];
+for (var i = 0; i < 10000; i++) {
+	records.push({Price: i * 0.01, Amount: i % 100});
+}
+records.sort(function(a, b) { return b.Amount - a.Amount; });
+JSON.parse(JSON.stringify(records)).length;
+`
+
+func benchmarkEngine(b *testing.B, name string) {
+	vm, err := newEngine(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vm.run("benchmark.js", benchmarkScript); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOtto(b *testing.B) {
+	benchmarkEngine(b, EngineOtto)
+}
+
+func BenchmarkGoja(b *testing.B) {
+	benchmarkEngine(b, EngineGoja)
+}
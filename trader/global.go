@@ -2,7 +2,7 @@ package trader
 
 import (
 	//"encoding/json"
-	//"fmt"
+	"fmt"
 	"log"
 	//"reflect"
 	"sync"
@@ -12,7 +12,7 @@ import (
 	"github.com/phonegapX/QuantBot/api"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
-	"github.com/robertkrimen/otto"
+	"github.com/phonegapX/QuantBot/notify"
 )
 
 type Tasks map[string][]task
@@ -20,48 +20,160 @@ type Tasks map[string][]task
 // Global ...
 type Global struct {
 	model.Trader
-	Logger  model.Logger   //利用这个对象保存日志
-	ctx     *otto.Otto     //js虚拟机
-	es      []api.Exchange //交易所列表
-	tasks   Tasks          //任务列表
-	running bool
+	Logger    model.Logger    //利用这个对象保存日志
+	vm        engine          //js虚拟机
+	es        []api.Exchange  //交易所列表
+	tasks     Tasks           //任务列表
+	running   bool            //任务组是否正在运行
+	forking   bool            //正在为任务创建新的虚拟机,期间执行的顶层代码不能再添加任务和定时器
+	mutex     sync.Mutex      //保护任务组状态,任务函数在不同的goroutine中运行
+	scheduler *scheduler      //定时器调度
+	limiter   *limiter        //脚本资源限制
+	heartbeat *heartbeat      //看门狗检查的心跳
+	modules   *modules        //require加载的模块库
+	owner     model.User      //策略的所有者
+	httpQuota httpQuota       //HttpQuery的频率限制
+	evalMutex sync.Mutex      //同一个策略同一时间只允许一个控制台表达式等待执行
+	filled    map[string]bool //已经发送过成交通知的订单
 	//statusLog string
 }
 
 //js中的一个任务,目的是可以并发工作
 type task struct {
-	ctx  *otto.Otto    //js虚拟机
-	fn   otto.Value    //代表该任务的js函数
+	vm   engine        //js虚拟机
+	fn   string        //代表该任务的js函数
 	args []interface{} //函数的参数
 }
 
+// TaskResult is the result of a task, Value is converted to plain data so it can be used safely in the main context
+type TaskResult struct {
+	Value    interface{} //任务函数的返回值
+	Error    string      //任务出错或者超时的错误信息
+	Duration int64       //任务的执行时间,单位毫秒
+}
+
 // Sleep ...
 func (g *Global) Sleep(intervals ...interface{}) {
 	interval := int64(0)
 	if len(intervals) > 0 {
 		interval = conver.Int64Must(intervals[0])
 	}
-	if interval > 0 {
-		time.Sleep(time.Duration(interval * 1000000))
-	} else {
-		for _, e := range g.es {
-			e.AutoSleep()
+	g.idle(func() {
+		if interval > 0 {
+			g.wait(time.Duration(interval * 1000000))
+		} else {
+			for _, e := range g.es {
+				e.AutoSleep()
+			}
+			g.runTimers()
 		}
+	})
+}
+
+//idle 执行Sleep、Receive等休眠的函数,主循环休眠期间不计入执行时间
+func (g *Global) idle(fn func()) {
+	//任务组运行期间的休眠来自任务的js虚拟机,不影响主循环的计时
+	if main := !g.tasksRunning(); main {
+		g.limiter.checkMemory(g.vm)
+		g.limiter.end()
+		g.heartbeat.sleep(true)
+		defer func() {
+			g.heartbeat.sleep(false)
+			g.limiter.begin()
+		}()
+	} else {
+		g.heartbeat.beat()
+	}
+	fn()
+}
+
+// SetInterval ...
+func (g *Global) SetInterval(name interface{}, interval interface{}, fn interface{}) bool {
+	return g.addTimer("SetInterval()", name, fn, func(name, fn string) (*Timer, error) {
+		return newIntervalTimer(name, fn, conver.Int64Must(interval), true)
+	})
+}
+
+// SetTimeout ...
+func (g *Global) SetTimeout(name interface{}, timeout interface{}, fn interface{}) bool {
+	return g.addTimer("SetTimeout()", name, fn, func(name, fn string) (*Timer, error) {
+		return newIntervalTimer(name, fn, conver.Int64Must(timeout), false)
+	})
+}
+
+// Cron ...
+func (g *Global) Cron(name interface{}, spec string, fn interface{}) bool {
+	return g.addTimer("Cron()", name, fn, func(name, fn string) (*Timer, error) {
+		return newCronTimer(name, fn, spec)
+	})
+}
+
+// ClearTimer ...
+func (g *Global) ClearTimer(name interface{}) bool {
+	if g.forking {
+		return false
+	}
+	timer, ok := name.(string)
+	if !ok {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ClearTimer(), Invalid timer name")
+		return false
+	}
+	return g.scheduler.cancel(timer)
+}
+
+func (g *Global) addTimer(method string, name interface{}, fn interface{}, maker func(name, fn string) (*Timer, error)) bool {
+	if g.forking {
+		return false
+	}
+	timer, ok := name.(string)
+	if !ok {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, method, ", Invalid timer name")
+		return false
+	}
+	function, ok := fn.(string)
+	if !ok {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, method, ", Invalid function name")
+		return false
+	}
+	t, err := maker(timer, function)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, method, ", ", err)
+		return false
 	}
+	g.scheduler.add(t)
+	return true
 }
 
 // Console ...
 func (g *Global) Console(msgs ...interface{}) {
+	g.heartbeat.beat()
 	log.Printf("%v %v\n", constant.INFO, msgs)
+	g.Logger.Log(constant.DEBUG, "", 0.0, 0.0, msgs...) //保存到策略的调试日志
 }
 
 // Log ...
 func (g *Global) Log(msgs ...interface{}) {
+	g.heartbeat.beat()
 	g.Logger.Log(constant.INFO, "", 0.0, 0.0, msgs...)
 }
 
+// Notify send a notification by a notify channel of the trader owner
+func (g *Global) Notify(channel string, title string, msgs ...interface{}) bool {
+	g.heartbeat.beat()
+	c, err := g.owner.GetNotifyChannel(channel)
+	if err == nil {
+		err = notify.Send(c, title, fmt.Sprint(msgs...))
+	}
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Notify(), ", err)
+		return false
+	}
+	return true
+}
+
 // LogProfit ...
 func (g *Global) LogProfit(msgs ...interface{}) {
+	g.heartbeat.beat()
 	profit := 0.0
 	if len(msgs) > 0 {
 		profit = conver.Float64Must(msgs[0])
@@ -89,50 +201,65 @@ func (g *Global) LogProfit(msgs ...interface{}) {
 //}
 
 // AddTask ...
-func (g *Global) AddTask(group otto.Value, fn otto.Value, args ...interface{}) bool {
-	if g.running {
+func (g *Global) AddTask(group interface{}, fn interface{}, args ...interface{}) bool {
+	if g.forking {
+		return false
+	}
+	if g.tasksRunning() {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddTask(), tasks are running")
 		return false
 	}
-	if !group.IsString() {
+	name, ok := group.(string)
+	if !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddTask(), Invalid group name")
 		return false
 	}
-	if !fn.IsString() {
+	function, ok := fn.(string)
+	if !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddTask(), Invalid function name")
 		return false
 	}
-	if _, ok := g.tasks[group.String()]; !ok {
-		g.tasks[group.String()] = []task{}
+	//创建任务的虚拟机时可能执行策略脚本,不能持有锁
+	vm, err := g.vm.fork(g.setupTask)
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "AddTask(), ", err)
+		return false
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if _, ok := g.tasks[name]; !ok {
+		g.tasks[name] = []task{}
 	}
-	t := task{ctx: g.ctx.Copy(), fn: fn, args: args}
-	t.ctx.Interrupt = make(chan func(), 1)
-	g.tasks[group.String()] = append(g.tasks[group.String()], t)
+	g.tasks[name] = append(g.tasks[name], task{vm: vm, fn: function, args: args})
 	return true
 }
 
 // BindTaskParam ...
-func (g *Global) BindTaskParam(group otto.Value, fn otto.Value, args ...interface{}) bool {
+func (g *Global) BindTaskParam(group interface{}, fn interface{}, args ...interface{}) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
 	if g.running {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "BindTaskParam(), tasks are running")
 		return false
 	}
-	if !group.IsString() {
+	name, ok := group.(string)
+	if !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "BindTaskParam(), Invalid group name")
 		return false
 	}
-	if !fn.IsString() {
+	function, ok := fn.(string)
+	if !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "BindTaskParam(), Invalid function name")
 		return false
 	}
-	if _, ok := g.tasks[group.String()]; !ok {
+	if _, ok := g.tasks[name]; !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "BindTaskParam(), group not exist")
 		return false
 	}
-	ts := g.tasks[group.String()]
+	ts := g.tasks[name]
 	for i := 0; i < len(ts); i++ {
 		t := &ts[i]
-		if t.fn.String() == fn.String() {
+		if t.fn == function {
 			t.args = args
 			return true
 		}
@@ -142,42 +269,104 @@ func (g *Global) BindTaskParam(group otto.Value, fn otto.Value, args ...interfac
 }
 
 // ExecTasks ...
-func (g *Global) ExecTasks(group otto.Value) (results []interface{}) {
-	if !group.IsString() {
+func (g *Global) ExecTasks(group interface{}, timeouts ...interface{}) (results []TaskResult) {
+	name, ok := group.(string)
+	if !ok {
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ExecTasks(), Invalid group name")
 		return
 	}
-	if _, ok := g.tasks[group.String()]; !ok {
+	g.mutex.Lock()
+	ts, ok := g.tasks[name]
+	if !ok {
+		g.mutex.Unlock()
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ExecTasks(), group not exist")
 		return
 	}
 	if g.running {
+		g.mutex.Unlock()
 		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ExecTasks(), tasks are running")
 		return
 	}
 	g.running = true
-	ts := g.tasks[group.String()]
-	for range ts {
-		results = append(results, false)
+	g.mutex.Unlock()
+	timeout := int64(0)
+	if len(timeouts) > 0 {
+		timeout = conver.Int64Must(timeouts[0])
 	}
+	results = make([]TaskResult, len(ts))
+	done := make([]bool, len(ts)) //结果已经确定的任务,超时之后返回的结果会被丢弃
 	wg := sync.WaitGroup{}
 	for i, t := range ts {
 		wg.Add(1)
 		go func(i int, t task) {
-			if f, err := t.ctx.Get(t.fn.String()); err != nil || !f.IsFunction() {
-				g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Can not get the task function")
-			} else {
-				result, err := f.Call(f, t.args...)
-				if err != nil || result.IsUndefined() || result.IsNull() {
-					results[i] = false
-				} else {
-					results[i] = result
-				}
+			defer wg.Done()
+			start := time.Now()
+			result := g.execTask(t)
+			result.Duration = time.Since(start).Nanoseconds() / 1000000
+			g.mutex.Lock()
+			if !done[i] {
+				done[i] = true
+				results[i] = result
 			}
-			wg.Done()
+			g.mutex.Unlock()
 		}(i, t)
 	}
-	wg.Wait()
-	g.running = false
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		g.mutex.Lock()
+		g.running = false
+		g.mutex.Unlock()
+		close(finished)
+	}()
+	if timeout <= 0 {
+		<-finished
+		return
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Duration(timeout) * time.Millisecond):
+		//通过中断通道结束超时的任务,在它们真正退出之前任务组仍然处于运行状态
+		g.mutex.Lock()
+		for i, t := range ts {
+			if done[i] {
+				continue
+			}
+			done[i] = true
+			results[i] = TaskResult{Error: errTaskTimeout.Error(), Duration: timeout}
+			t.vm.interrupt(errTaskTimeout)
+		}
+		g.mutex.Unlock()
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "ExecTasks(), some tasks of ", name, " timeout")
+	}
+	return
+}
+
+//execTask 在任务自己的js虚拟机上执行任务函数
+func (g *Global) execTask(t task) (result TaskResult) {
+	defer func() {
+		if err := recover(); err != nil {
+			result.Error = fmt.Sprint(err)
+		}
+	}()
+	t.vm.clearInterrupt() //丢弃上一次执行遗留的中断
+	if !t.vm.has(t.fn) {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Can not get the task function")
+		result.Error = "Can not get the task function"
+		return
+	}
+	value, err := t.vm.call(t.fn, t.args...)
+	if err != nil {
+		result.Error = fmt.Sprint(err)
+		return
+	}
+	result.Value = value
 	return
 }
+
+//tasksRunning 任务组是否正在运行
+func (g *Global) tasksRunning() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.running
+}
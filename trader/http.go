@@ -0,0 +1,167 @@
+package trader
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+//脚本HTTP请求的默认限制,配置文件中没有设置时使用
+const (
+	httpDefaultRateLimit    = 60          //每个策略每分钟的最大请求次数
+	httpDefaultRequestSize  = 64 * 1024   //请求体的最大字节数
+	httpDefaultResponseSize = 1024 * 1024 //响应体的最大字节数
+	httpDefaultTimeout      = 10000       //请求的默认超时时间,单位毫秒
+	httpRateWindow          = time.Minute
+)
+
+// HttpResponse is the result of G.HttpQuery
+type HttpResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    string
+}
+
+//脚本中G.HttpQuery的选项
+type httpOptions struct {
+	Method  string
+	Headers map[string]string
+	Body    string
+	Timeout int64 //毫秒
+}
+
+//每个策略的HTTP请求频率限制,任务中也可以发送请求
+type httpQuota struct {
+	mutex    sync.Mutex
+	requests []time.Time //最近一分钟内的请求时间
+}
+
+//allow 是否允许再发送一次请求,允许时记录本次请求
+func (q *httpQuota) allow(limit int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	now := time.Now()
+	i := 0
+	for i < len(q.requests) && now.Sub(q.requests[i]) >= httpRateWindow {
+		i++
+	}
+	q.requests = q.requests[i:]
+	if len(q.requests) >= limit {
+		return false
+	}
+	q.requests = append(q.requests, now)
+	return true
+}
+
+//checkHTTPURL 只允许访问管理员在httpAllowHosts中设置的主机的http和https地址
+func checkHTTPURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("Unsupported scheme %v", u.Scheme)
+	}
+	if !config.HostAllowed("httpallowhosts", u.Hostname()) {
+		return fmt.Errorf("Host %v is not allowed", u.Hostname())
+	}
+	return nil
+}
+
+//httpQuery 发送请求并读取响应,重定向的地址同样需要在白名单中
+func httpQuery(rawurl string, opt httpOptions) (resp HttpResponse, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+	if err = checkHTTPURL(u); err != nil {
+		return
+	}
+	if max := config.Int("httpmaxrequestsize", httpDefaultRequestSize); len(opt.Body) > max {
+		err = fmt.Errorf("Request body is larger than %v bytes", max)
+		return
+	}
+	if opt.Method != "GET" && opt.Method != "POST" {
+		err = fmt.Errorf("Unsupported method %v", opt.Method)
+		return
+	}
+	req, err := http.NewRequest(opt.Method, u.String(), strings.NewReader(opt.Body))
+	if err != nil {
+		return
+	}
+	for k, v := range opt.Headers {
+		req.Header.Set(k, v)
+	}
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = int64(config.Int("httptimeout", httpDefaultTimeout))
+	}
+	client := &http.Client{
+		Timeout: time.Duration(timeout) * time.Millisecond,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("Stopped after 10 redirects")
+			}
+			return checkHTTPURL(req.URL)
+		},
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	max := config.Int("httpmaxresponsesize", httpDefaultResponseSize)
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, int64(max)+1))
+	if err != nil {
+		return
+	}
+	if len(body) > max {
+		err = fmt.Errorf("Response body is larger than %v bytes", max)
+		return
+	}
+	resp.Status = res.StatusCode
+	resp.Headers = make(map[string]string)
+	for k := range res.Header {
+		resp.Headers[k] = res.Header.Get(k)
+	}
+	resp.Body = string(body)
+	return
+}
+
+// HttpQuery send a GET/POST request to an allowed host, options: {Method, Headers, Body, Timeout}
+func (g *Global) HttpQuery(rawurl string, options ...interface{}) interface{} {
+	g.heartbeat.beat()
+	defer g.heartbeat.beat()
+	opt := httpOptions{}
+	if len(options) > 0 && options[0] != nil {
+		if err := mapstructure.WeakDecode(options[0], &opt); err != nil {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "HttpQuery(), Invalid options: ", err)
+			return nil
+		}
+	}
+	if opt.Method = strings.ToUpper(opt.Method); opt.Method == "" {
+		opt.Method = "GET"
+	}
+	if !g.httpQuota.allow(config.Int("httpratelimit", httpDefaultRateLimit)) {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "HttpQuery(), rate limit exceeded")
+		return nil
+	}
+	resp, err := httpQuery(rawurl, opt)
+	result := fmt.Sprintf("%v, %v bytes", resp.Status, len(resp.Body))
+	if err != nil {
+		result = fmt.Sprint("Error: ", err)
+	}
+	if err := g.owner.CreateAudit(g.ID, "HttpQuery", fmt.Sprintf("%v %v", opt.Method, rawurl), result); err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "HttpQuery(), ", err)
+	}
+	if err != nil {
+		g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "HttpQuery(), ", err)
+		return nil
+	}
+	return resp
+}
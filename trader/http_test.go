@@ -0,0 +1,92 @@
+package trader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/phonegapX/QuantBot/config"
+)
+
+//测试服务器监听127.0.0.1,config.ini中只允许访问这个地址,localhost不在白名单中
+func newHTTPTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", config.Int("httpmaxresponsesize", 0)+1)))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPQueryAllowHosts(t *testing.T) {
+	server := newHTTPTestServer()
+	defer server.Close()
+	resp, err := httpQuery(server.URL+"/ok", httpOptions{Method: "GET"})
+	if err != nil || resp.Status != http.StatusOK || resp.Body != "ok" {
+		t.Fatalf("allowed host: %v, %v", resp, err)
+	}
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	if _, err := httpQuery(local+"/ok", httpOptions{Method: "GET"}); err == nil {
+		t.Fatal("host which is not allowed is accessed")
+	}
+	if _, err := httpQuery("file:///etc/passwd", httpOptions{Method: "GET"}); err == nil {
+		t.Fatal("unsupported scheme is accessed")
+	}
+	if _, err := httpQuery(server.URL+"/ok", httpOptions{Method: "DELETE"}); err == nil {
+		t.Fatal("unsupported method is sent")
+	}
+}
+
+func TestHTTPQueryRedirect(t *testing.T) {
+	server := newHTTPTestServer()
+	defer server.Close()
+	resp, err := httpQuery(server.URL+"/redirect?to="+server.URL+"/ok", httpOptions{Method: "GET"})
+	if err != nil || resp.Body != "ok" {
+		t.Fatalf("redirect to allowed host: %v, %v", resp, err)
+	}
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	if _, err := httpQuery(server.URL+"/redirect?to="+local+"/ok", httpOptions{Method: "GET"}); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("redirect to host which is not allowed: %v", err)
+	}
+}
+
+func TestHTTPQueryRateLimit(t *testing.T) {
+	limit := config.Int("httpratelimit", httpDefaultRateLimit)
+	quota := httpQuota{}
+	for i := 0; i < limit; i++ {
+		if !quota.allow(limit) {
+			t.Fatalf("request %v is limited", i+1)
+		}
+	}
+	if quota.allow(limit) {
+		t.Fatalf("more than %v requests are allowed", limit)
+	}
+}
+
+func TestHTTPQuerySizeLimit(t *testing.T) {
+	server := newHTTPTestServer()
+	defer server.Close()
+	max := config.Int("httpmaxrequestsize", httpDefaultRequestSize)
+	body := strings.Repeat("a", max)
+	resp, err := httpQuery(server.URL+"/echo", httpOptions{Method: "POST", Body: body})
+	if err != nil || resp.Body != body {
+		t.Fatalf("request body of max size: %v", err)
+	}
+	if _, err := httpQuery(server.URL+"/echo", httpOptions{Method: "POST", Body: body + "a"}); err == nil {
+		t.Fatal("request body larger than max size is sent")
+	}
+	if _, err := httpQuery(server.URL+"/large", httpOptions{Method: "GET"}); err == nil || !strings.Contains(err.Error(), "larger") {
+		t.Fatalf("response body larger than max size: %v", err)
+	}
+}
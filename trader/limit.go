@@ -0,0 +1,117 @@
+package trader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+)
+
+// Limit Variable
+var (
+	errCPULimit    = fmt.Errorf("CPU LIMIT")
+	errMemoryLimit = fmt.Errorf("MEMORY LIMIT")
+	limitInterval  = time.Second      //检查脚本执行时间的间隔
+	memoryInterval = 10 * time.Second //统计脚本对象数量的间隔
+	bindings       = map[string]bool{ //不统计的全局绑定对象
+		"Global": true, "G": true, "Exchange": true, "E": true, "Exchanges": true, "Es": true, "require": true,
+	}
+)
+
+//脚本的资源限制,单次循环(两次Sleep之间)的执行时间和脚本全局可达的对象数量,0表示不限制
+type limiter struct {
+	maxIteration time.Duration
+	maxObjects   int64
+
+	mutex     sync.Mutex
+	start     time.Time //本次循环开始的时间,休眠期间为零值
+	lastCount time.Time //上一次统计对象数量的时间
+	exceeded  error     //已经超出的限制
+	done      chan struct{}
+}
+
+//configLimit 获取策略的限制值,策略没有设置时使用配置文件中的默认值
+func configLimit(value int64, key string) int64 {
+	if value > 0 {
+		return value
+	}
+	return int64(config.Int(key, 0))
+}
+
+func newLimiter(trader *Global) *limiter {
+	return &limiter{
+		maxIteration: time.Duration(configLimit(trader.CPULimit, "scriptmaxiterationtime")) * time.Millisecond,
+		maxObjects:   configLimit(trader.ObjectLimit, "scriptmaxobjects"),
+		lastCount:    time.Now(),
+		done:         make(chan struct{}),
+	}
+}
+
+//begin 开始一次循环的计时
+func (l *limiter) begin() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.start = time.Now()
+}
+
+//end 结束一次循环的计时
+func (l *limiter) end() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.start = time.Time{}
+}
+
+//watch 监视脚本的执行时间,超出限制时通过中断通道结束脚本
+func (l *limiter) watch(vm engine) {
+	if l.maxIteration <= 0 {
+		return
+	}
+	ticker := time.NewTicker(limitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			l.mutex.Lock()
+			exceeded := l.exceeded == nil && !l.start.IsZero() && time.Since(l.start) > l.maxIteration
+			if exceeded {
+				l.exceeded = errCPULimit
+			}
+			l.mutex.Unlock()
+			if exceeded {
+				vm.interrupt(errCPULimit)
+			}
+		}
+	}
+}
+
+func (l *limiter) stop() {
+	close(l.done)
+}
+
+//checkMemory 定期统计脚本全局可达的对象数量,只能在主js虚拟机所在的goroutine中调用
+func (l *limiter) checkMemory(vm engine) {
+	if l.maxObjects <= 0 || time.Since(l.lastCount) < memoryInterval {
+		return
+	}
+	l.lastCount = time.Now()
+	if vm.countObjects(bindings, l.maxObjects) {
+		l.mutex.Lock()
+		l.exceeded = errMemoryLimit
+		l.mutex.Unlock()
+		panic(errMemoryLimit)
+	}
+}
+
+//limitMessage 资源超限时的日志信息
+func (l *limiter) limitMessage(err interface{}) string {
+	switch err {
+	case errCPULimit:
+		return fmt.Sprintf("The script has been stopped, it ran more than %v without calling Sleep()", l.maxIteration)
+	case errMemoryLimit:
+		return fmt.Sprintf("The script has been stopped, it holds more than %v objects", l.maxObjects)
+	}
+	return ""
+}
@@ -1,22 +1,62 @@
 package trader
 
 import (
+	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/robertkrimen/otto/registry"
 )
 
+//插件文件的检查间隔,文件变化后新启动的策略使用新的插件
+const pluginInterval = 10 * time.Second
+
 var (
-	scripts = []string{}
-	entry   = registry.Register(func() string {
-		return strings.Join(scripts, "")
-	})
+	scripts      = []string{}
+	scriptsMutex sync.RWMutex
+	entry        = registry.Register(pluginScript)
 )
 
+//pluginScript 所有插件的脚本,otto通过registry加载,其他脚本引擎在创建虚拟机时执行
+func pluginScript() string {
+	scriptsMutex.RLock()
+	defer scriptsMutex.RUnlock()
+	return strings.Join(scripts, "")
+}
+
 func init() {
+	signature := loadPlugins()
+	go func() {
+		for {
+			time.Sleep(pluginInterval)
+			if s := pluginSignature(); s != signature {
+				signature = loadPlugins()
+				log.Println("Plugins reloaded")
+			}
+		}
+	}()
+}
+
+//pluginSignature 插件文件的路径、大小和修改时间,用来判断插件是否有变化
+func pluginSignature() (signature string) {
+	filepath.Walk("plugin", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".js") {
+			return err
+		}
+		signature += fmt.Sprintf("%v:%v:%v;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return
+}
+
+//loadPlugins 重新读取所有的插件文件
+func loadPlugins() (signature string) {
+	plugins := []string{}
 	filepath.Walk("plugin", func(path string, info os.FileInfo, err error) error {
 		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".js") {
 			return err
@@ -27,7 +67,12 @@ func init() {
 		}
 		defer file.Close()
 		data, _ := ioutil.ReadAll(file)
-		scripts = append(scripts, string(data))
+		plugins = append(plugins, string(data))
+		signature += fmt.Sprintf("%v:%v:%v;", path, info.Size(), info.ModTime().UnixNano())
 		return nil
 	})
+	scriptsMutex.Lock()
+	defer scriptsMutex.Unlock()
+	scripts = plugins
+	return
 }
@@ -0,0 +1,81 @@
+package trader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/phonegapX/QuantBot/model"
+)
+
+//策略通过require加载的用户模块库,每个js虚拟机有自己的模块
+type modules struct {
+	user    model.User             //策略的所有者,只能加载他可以访问的模块库
+	exports map[string]interface{} //已经加载的模块,同一个模块只执行一次
+	loading []string               //正在加载的模块,用来检测循环依赖
+}
+
+func newModules(user model.User) *modules {
+	return &modules{
+		user:    user,
+		exports: make(map[string]interface{}),
+	}
+}
+
+//parseModuleName 解析"name"或者"name@version"形式的模块名,版本为0表示最新版本
+func parseModuleName(spec string) (name string, version int64, err error) {
+	name = spec
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		name = spec[:i]
+		if version, err = strconv.ParseInt(spec[i+1:], 10, 64); err != nil || version <= 0 {
+			return "", 0, fmt.Errorf("Invalid module version %v", spec)
+		}
+	}
+	if name == "" {
+		return "", 0, fmt.Errorf("Invalid module name %v", spec)
+	}
+	return
+}
+
+//require 返回绑定到js虚拟机vm的require函数,返回模块的module.exports
+func (g *Global) require(vm engine, m *modules) func(spec string) interface{} {
+	return func(spec string) interface{} {
+		exports, err := g.loadModule(vm, m, spec)
+		if err != nil {
+			//嵌套require的错误已经是RequireError,不重复添加前缀
+			vm.throw("RequireError", strings.TrimPrefix(err.Error(), "RequireError: "))
+		}
+		return exports
+	}
+}
+
+func (g *Global) loadModule(vm engine, m *modules, spec string) (exports interface{}, err error) {
+	if g.tasksRunning() {
+		return nil, fmt.Errorf("require() can not be called in tasks")
+	}
+	name, version, err := parseModuleName(spec)
+	if err != nil {
+		return
+	}
+	if exports, ok := m.exports[spec]; ok {
+		return exports, nil
+	}
+	for i, n := range m.loading {
+		if n == name {
+			return nil, fmt.Errorf("Cyclic require: %v -> %v", strings.Join(m.loading[i:], " -> "), name)
+		}
+	}
+	library, err := m.user.FindLibrary(name, version)
+	if err != nil {
+		return
+	}
+	m.loading = append(m.loading, name)
+	defer func() {
+		m.loading = m.loading[:len(m.loading)-1]
+	}()
+	if exports, err = vm.module(fmt.Sprintf("%v@%v.js", library.Name, library.Version), library.Script); err != nil {
+		return
+	}
+	m.exports[spec] = exports
+	return
+}
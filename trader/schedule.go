@@ -0,0 +1,119 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/phonegapX/QuantBot/model"
+	"github.com/robfig/cron"
+)
+
+//自动启停的检查间隔,cron表达式最小精度为分钟
+const scheduleInterval = 20 * time.Second
+
+func init() {
+	go schedule()
+}
+
+//scheduleLocation 返回策略cron表达式的时区,默认使用日志的时区
+func scheduleLocation(timezone string) (loc *time.Location, err error) {
+	if timezone == "" {
+		timezone = config.String("logstimezone")
+	}
+	return time.LoadLocation(timezone)
+}
+
+// CheckSchedule check the start/stop cron expressions and the timezone of a trader
+func CheckSchedule(trader model.Trader) (err error) {
+	if _, err = scheduleLocation(trader.Timezone); err != nil {
+		return
+	}
+	if trader.StartCron != "" {
+		if _, err = cron.ParseStandard(trader.StartCron); err != nil {
+			return fmt.Errorf("Invalid start cron: %v", err)
+		}
+	}
+	if trader.StopCron != "" {
+		if _, err = cron.ParseStandard(trader.StopCron); err != nil {
+			return fmt.Errorf("Invalid stop cron: %v", err)
+		}
+	}
+	return
+}
+
+// NextSchedule get the next automatic start and stop time of a trader
+func NextSchedule(trader model.Trader) (start, stop time.Time) {
+	loc, err := scheduleLocation(trader.Timezone)
+	if err != nil {
+		return
+	}
+	now := time.Now().In(loc)
+	if s, err := cron.ParseStandard(trader.StartCron); trader.StartCron != "" && err == nil {
+		start = s.Next(now)
+	}
+	if s, err := cron.ParseStandard(trader.StopCron); trader.StopCron != "" && err == nil {
+		stop = s.Next(now)
+	}
+	return
+}
+
+//due 判断cron表达式在(last, now]之间是否有触发点
+func due(spec string, loc *time.Location, last, now time.Time) bool {
+	if spec == "" {
+		return false
+	}
+	s, err := cron.ParseStandard(spec)
+	if err != nil {
+		return false
+	}
+	return !s.Next(last.In(loc)).After(now)
+}
+
+//schedule 按照策略设置的cron表达式自动启动和停止策略
+func schedule() {
+	last := time.Now()
+	for {
+		time.Sleep(scheduleInterval)
+		now := time.Now()
+		traders := []model.Trader{}
+		if err := model.DB.Where("start_cron <> '' OR stop_cron <> ''").Find(&traders).Error; err != nil {
+			log.Println("Load scheduled traders error:", err)
+			continue
+		}
+		for _, t := range traders {
+			loc, err := scheduleLocation(t.Timezone)
+			if err != nil {
+				continue
+			}
+			autoSwitch(t, due(t.StartCron, loc, last, now), due(t.StopCron, loc, last, now))
+		}
+		last = now
+	}
+}
+
+//autoSwitch 执行一次自动启停,同时到期时以停止为准
+func autoSwitch(t model.Trader, startDue, stopDue bool) {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	logger := model.Logger{TraderID: t.ID, ExchangeType: "global"}
+	running := getTraderStatus(t.ID) > 0
+	switch {
+	case stopDue && running:
+		if err := stop(t.ID); err != nil {
+			logger.Log(constant.ERROR, "", 0.0, 0.0, "Scheduled stop error, ", err)
+			return
+		}
+		logger.Log(constant.INFO, "", 0.0, 0.0, "Scheduled stop, ", t.StopCron)
+		log.Printf("Trader %v scheduled stop\n", t.ID)
+	case startDue && !stopDue && !running:
+		if err := run(t.ID); err != nil {
+			logger.Log(constant.ERROR, "", 0.0, 0.0, "Scheduled start error, ", err)
+			return
+		}
+		logger.Log(constant.INFO, "", 0.0, 0.0, "Scheduled start, ", t.StartCron)
+		log.Printf("Trader %v scheduled start\n", t.ID)
+	}
+}
@@ -0,0 +1,191 @@
+package trader
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/robfig/cron"
+)
+
+// timer kinds
+const (
+	timerInterval = "interval"
+	timerTimeout  = "timeout"
+	timerCron     = "cron"
+)
+
+// Timer is a function of the script which is called on a schedule
+type Timer struct {
+	Name     string    //定时器名称,同名的定时器会被替换
+	Kind     string    //interval, timeout 或者 cron
+	Spec     string    //间隔毫秒数或者cron表达式
+	Function string    //被调用的js函数名
+	NextRun  time.Time //下一次执行的时间
+
+	interval time.Duration
+	schedule cron.Schedule
+}
+
+//每个策略一个调度器,定时器只在策略的主js虚拟机上执行(G.Sleep()期间),所以脚本仍然是单线程的
+type scheduler struct {
+	mutex  sync.Mutex
+	timers map[string]*Timer
+	firing bool //正在执行定时器函数,防止在定时器函数中调用Sleep时重入
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{timers: make(map[string]*Timer)}
+}
+
+func newIntervalTimer(name, fn string, ms int64, repeat bool) (t *Timer, err error) {
+	if ms <= 0 {
+		err = fmt.Errorf("Invalid interval %v", ms)
+		return
+	}
+	t = &Timer{
+		Name:     name,
+		Kind:     timerTimeout,
+		Spec:     fmt.Sprint(ms),
+		Function: fn,
+		interval: time.Duration(ms) * time.Millisecond,
+	}
+	if repeat {
+		t.Kind = timerInterval
+	}
+	t.NextRun = time.Now().Add(t.interval)
+	return
+}
+
+//cron表达式统一按UTC时间计算
+func newCronTimer(name, fn, spec string) (t *Timer, err error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return
+	}
+	t = &Timer{
+		Name:     name,
+		Kind:     timerCron,
+		Spec:     spec,
+		Function: fn,
+		NextRun:  schedule.Next(time.Now().UTC()),
+		schedule: schedule,
+	}
+	return
+}
+
+func (s *scheduler) add(t *Timer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timers[t.Name] = t
+}
+
+func (s *scheduler) cancel(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.timers[name]; !ok {
+		return false
+	}
+	delete(s.timers, name)
+	return true
+}
+
+func (s *scheduler) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timers = make(map[string]*Timer)
+}
+
+//list 返回按下一次执行时间排序的定时器列表
+func (s *scheduler) list() (timers []Timer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, t := range s.timers {
+		timers = append(timers, *t)
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].NextRun.Before(timers[j].NextRun)
+	})
+	return
+}
+
+//next 返回最近一个定时器的执行时间
+func (s *scheduler) next() (next time.Time, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, t := range s.timers {
+		if !ok || t.NextRun.Before(next) {
+			next, ok = t.NextRun, true
+		}
+	}
+	return
+}
+
+//due 取出所有到期的定时器,并计算它们的下一次执行时间
+func (s *scheduler) due(now time.Time) (timers []Timer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for name, t := range s.timers {
+		if now.Before(t.NextRun) {
+			continue
+		}
+		timers = append(timers, *t)
+		switch t.Kind {
+		case timerInterval:
+			t.NextRun = now.Add(t.interval)
+		case timerCron:
+			t.NextRun = t.schedule.Next(now.UTC())
+		default:
+			delete(s.timers, name)
+		}
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].NextRun.Before(timers[j].NextRun)
+	})
+	return
+}
+
+//runTimers 在主js虚拟机上依次执行控制台等待的表达式和到期的定时器函数,任务组运行期间的Sleep来自其他虚拟机,不执行定时器
+func (g *Global) runTimers() {
+	if g.scheduler.firing || g.tasksRunning() {
+		return
+	}
+	g.scheduler.firing = true
+	defer func() {
+		g.scheduler.firing = false
+	}()
+	g.vm.poll()
+	for _, t := range g.scheduler.due(time.Now()) {
+		if !g.vm.has(t.Function) {
+			g.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Can not get the timer function ", t.Function)
+			continue
+		}
+		//定时器在Sleep中执行,执行期间恢复心跳检查,死循环的定时器同样会被看门狗中断
+		g.heartbeat.sleep(false)
+		g.limiter.begin()
+		if _, err := g.vm.call(t.Function, t.Name); err != nil {
+			g.logError(err)
+		}
+		g.limiter.end()
+		g.heartbeat.sleep(true)
+	}
+}
+
+//wait 休眠指定的时间,期间到期的定时器会被执行
+func (g *Global) wait(d time.Duration) {
+	deadline := time.Now().Add(d)
+	for {
+		g.runTimers()
+		now := time.Now()
+		if !now.Before(deadline) {
+			return
+		}
+		wake := deadline
+		if next, ok := g.scheduler.next(); ok && next.Before(wake) && !g.scheduler.firing && !g.tasksRunning() {
+			wake = next
+		}
+		time.Sleep(wake.Sub(now))
+	}
+}
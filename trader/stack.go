@@ -0,0 +1,94 @@
+package trader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	gparser "github.com/dop251/goja/parser"
+	"github.com/phonegapX/QuantBot/constant"
+	"github.com/robertkrimen/otto"
+	"github.com/robertkrimen/otto/parser"
+)
+
+// Stack Variable
+var (
+	scriptFilename = "algorithm.js" //编译策略脚本时使用的文件名,用来区分插件脚本的堆栈
+	sourceContext  = 3              //错误行前后显示的源码行数
+	framePattern   = regexp.MustCompile(regexp.QuoteMeta(scriptFilename) + `:(\d+):(\d+)`)
+)
+
+// ScriptError is a script error with the js stack and the source lines around the failing line
+type ScriptError struct {
+	Message string
+	Stack   string
+	Line    int
+	Column  int
+	Source  []SourceLine
+}
+
+// SourceLine is a line of the script
+type SourceLine struct {
+	Line int
+	Text string
+}
+
+//newScriptError 从otto或goja的错误中解析出堆栈和出错的位置
+func newScriptError(err error, script string) (e ScriptError) {
+	e.Message = err.Error()
+	switch err := err.(type) {
+	case *otto.Error:
+		e.Stack = err.String()
+		if m := framePattern.FindStringSubmatch(e.Stack); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Column, _ = strconv.Atoi(m[2])
+		}
+	case parser.ErrorList:
+		if len(err) > 0 {
+			e.Message = err[0].Error()
+			e.Line, e.Column = err[0].Position.Line, err[0].Position.Column
+		}
+	case *parser.Error:
+		e.Line, e.Column = err.Position.Line, err.Position.Column
+	case *goja.Exception:
+		e.Stack = err.String()
+		if m := framePattern.FindStringSubmatch(e.Stack); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Column, _ = strconv.Atoi(m[2])
+		}
+	case gparser.ErrorList:
+		if len(err) > 0 {
+			e.Message = err[0].Error()
+			e.Line, e.Column = err[0].Position.Line, err[0].Position.Column
+		}
+	case *gparser.Error:
+		e.Line, e.Column = err.Position.Line, err.Position.Column
+	case *goja.CompilerSyntaxError:
+		if err.File != nil {
+			p := err.File.Position(err.Offset)
+			e.Line, e.Column = p.Line, p.Column
+		}
+	}
+	if e.Line <= 0 {
+		return
+	}
+	lines := strings.Split(script, "\n")
+	for i := e.Line - sourceContext; i <= e.Line+sourceContext; i++ {
+		if i > 0 && i <= len(lines) {
+			e.Source = append(e.Source, SourceLine{Line: i, Text: strings.TrimRight(lines[i-1], "\r")})
+		}
+	}
+	return
+}
+
+//logError 记录脚本错误,包括完整的js堆栈和出错位置附近的源码
+func (g *Global) logError(err error) {
+	e := newScriptError(err, g.Algorithm.Script)
+	if e.Line > 0 {
+		g.Logger.LogDetail(constant.ERROR, e, fmt.Sprintf("%v (line %v, column %v)", e.Message, e.Line, e.Column))
+		return
+	}
+	g.Logger.LogDetail(constant.ERROR, e, e.Message)
+}
@@ -2,19 +2,22 @@ package trader
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/phonegapX/QuantBot/api"
 	"github.com/phonegapX/QuantBot/constant"
 	"github.com/phonegapX/QuantBot/model"
-	"github.com/robertkrimen/otto"
+	"github.com/phonegapX/QuantBot/notify"
 )
 
 // Trader Variable
 var (
-	Executor      = make(map[int64]*Global) //保存正在运行的策略，防止重复运行
-	errHalt       = fmt.Errorf("HALT")
-	exchangeMaker = map[string]func(api.Option) api.Exchange{ //保存所有交易所的构造函数
+	Executor       = make(map[int64]*Global) //保存正在运行的策略，防止重复运行
+	executorMutex  sync.Mutex                //管理台和自动调度都会启停策略
+	errHalt        = fmt.Errorf("HALT")
+	errTaskTimeout = fmt.Errorf("TIMEOUT")
+	exchangeMaker  = map[string]func(api.Option) api.Exchange{ //保存所有交易所的构造函数
 		constant.Zb:         api.NewZb,
 		constant.Okex:       api.NewOKEX,
 		constant.Huobi:      api.NewHuobi,
@@ -26,27 +29,67 @@ var (
 	}
 )
 
+// NewExchange create an exchange which is not bound to a trader
+func NewExchange(opt api.Option) (api.Exchange, error) {
+	maker, ok := exchangeMaker[opt.Type]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported exchange type %v", opt.Type)
+	}
+	return maker(opt), nil
+}
+
 // GetTraderStatus ...
 func GetTraderStatus(id int64) (status int64) {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	return getTraderStatus(id)
+}
+
+func getTraderStatus(id int64) (status int64) {
 	if t, ok := Executor[id]; ok && t != nil {
 		status = t.Status
 	}
 	return
 }
 
+// Status ...
+type Status struct {
+	Status       int64
+	HeartbeatAge int64
+	Timers       []Timer
+}
+
+// GetStatus ...
+func GetStatus(id int64) (status Status) {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	if t, ok := Executor[id]; ok && t != nil {
+		status.Status = t.Status
+		status.Timers = t.scheduler.list()
+		if t.Status > 0 {
+			status.HeartbeatAge = t.heartbeat.age().Nanoseconds() / 1000000
+		}
+	}
+	return
+}
+
 // Switch ...
 func Switch(id int64) (err error) {
-	if GetTraderStatus(id) > 0 {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	if getTraderStatus(id) > 0 {
 		return stop(id)
 	}
 	return run(id)
 }
 
 //核心是初始化js运行环境，及其可以调用的api
-func initialize(id int64) (trader Global, err error) {
+func initialize(id int64) (trader *Global, err error) {
 	if t := Executor[id]; t != nil && t.Status > 0 {
+		err = fmt.Errorf("The Trader is running")
 		return
 	}
+	trader = &Global{}
 	err = model.DB.First(&trader.Trader, id).Error
 	if err != nil {
 		return
@@ -63,6 +106,21 @@ func initialize(id int64) (trader Global, err error) {
 	if err != nil {
 		return
 	}
+	if trader.PinVersion > 0 {
+		version, err := model.GetAlgorithmVersion(trader.AlgorithmID, trader.PinVersion)
+		if err != nil {
+			return nil, fmt.Errorf("Can not found the algorithm version %v", trader.PinVersion)
+		}
+		trader.Algorithm.Script = version.Script
+		trader.Algorithm.Engine = version.Engine
+		trader.Algorithm.EvnDefault = version.EvnDefault
+		trader.RunVersion = version.Version
+	} else if trader.RunVersion, err = model.LastAlgorithmVersion(trader.AlgorithmID); err != nil {
+		return
+	}
+	if err = model.DB.Model(&trader.Trader).UpdateColumn("run_version", trader.RunVersion).Error; err != nil {
+		return
+	}
 	es, err := self.GetTraderExchanges(trader.ID)
 	if err != nil {
 		return
@@ -72,10 +130,13 @@ func initialize(id int64) (trader Global, err error) {
 		ExchangeType: "global",
 	}
 	trader.tasks = make(Tasks)
-	trader.ctx = otto.New()
-	trader.ctx.Interrupt = make(chan func(), 1)
-	for _, c := range constant.Consts {
-		trader.ctx.Set(c, c)
+	trader.scheduler = newScheduler()
+	trader.limiter = newLimiter(trader)
+	trader.heartbeat = newHeartbeat()
+	trader.modules = newModules(self)
+	trader.owner = self
+	if trader.vm, err = newEngine(trader.Algorithm.Engine); err != nil {
+		return
 	}
 	for _, e := range es {
 		if maker, ok := exchangeMaker[e.Type]; ok {
@@ -93,15 +154,69 @@ func initialize(id int64) (trader Global, err error) {
 		err = fmt.Errorf("Please add at least one exchange")
 		return
 	}
-	trader.ctx.Set("Global", &trader)
-	trader.ctx.Set("G", &trader)
-	trader.ctx.Set("Exchange", trader.es[0])
-	trader.ctx.Set("E", trader.es[0])
-	trader.ctx.Set("Exchanges", trader.es)
-	trader.ctx.Set("Es", trader.es)
+	err = trader.bind(trader.vm)
 	return
 }
 
+//bind 绑定全局常量、Global、交易所和require到js虚拟机
+func (g *Global) bind(vm engine) (err error) {
+	for _, c := range constant.Consts {
+		if err = vm.set(c, c); err != nil {
+			return
+		}
+	}
+	if err = vm.set("Global", g); err != nil {
+		return
+	}
+	if err = vm.set("G", g); err != nil {
+		return
+	}
+	m := g.modules
+	if vm != g.vm {
+		m = newModules(g.modules.user) //任务的虚拟机有自己的模块
+	}
+	if err = vm.set("require", g.require(vm, m)); err != nil {
+		return
+	}
+	return g.bindExchanges(vm)
+}
+
+//setupTask 初始化任务使用的新虚拟机,不能复制虚拟机的脚本引擎需要重新执行策略脚本
+func (g *Global) setupTask(vm engine) (err error) {
+	g.forking = true
+	defer func() {
+		g.forking = false
+	}()
+	if err = g.bind(vm); err != nil {
+		return
+	}
+	return vm.run(scriptFilename, g.Algorithm.Script)
+}
+
+//notify 把策略的系统事件发送到所有者订阅了该事件的通知渠道
+func (g *Global) notify(event, message string) {
+	go notify.Event(g.owner, event, fmt.Sprintf("Trader %v", g.Name), message)
+}
+
+//notifyFilled GetOrder返回完全成交的订单时发送成交通知,每个订单只通知一次
+func (g *Global) notifyFilled(e api.Exchange, result interface{}) {
+	order, ok := result.(api.Order)
+	if !ok || order.Amount <= 0 || order.DealAmount < order.Amount {
+		return
+	}
+	key := e.GetName() + "/" + order.ID
+	g.mutex.Lock()
+	if g.filled == nil {
+		g.filled = make(map[string]bool)
+	}
+	notified := g.filled[key]
+	g.filled[key] = true
+	g.mutex.Unlock()
+	if !notified {
+		g.notify(constant.EventOrderFilled, fmt.Sprintf("%v %v %v order %v filled, price %v, amount %v", e.GetName(), order.StockType, order.TradeType, order.ID, order.Price, order.DealAmount))
+	}
+}
+
 // run ...
 func run(id int64) (err error) {
 	trader, err := initialize(id)
@@ -110,30 +225,55 @@ func run(id int64) (err error) {
 	}
 	go func() {
 		defer func() {
-			if err := recover(); err != nil && err != errHalt {
+			trader.limiter.stop()
+			trader.scheduler.stop()
+			bus.unsubscribeAll(trader.ID)
+			err := recover()
+			if err == errCPULimit || err == errMemoryLimit {
+				//超出资源限制的脚本不再执行exit函数,策略进入错误状态
+				trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, trader.limiter.limitMessage(err))
+				trader.notify(constant.EventResourceLimit, trader.limiter.limitMessage(err))
+				trader.Status = -1
+				return
+			}
+			if err == errHung {
+				trader.notify(constant.EventTraderCrashed, "Interrupted by the watchdog")
+				trader.Status = -1
+				return
+			}
+			if err != nil && err != errHalt {
 				trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+				trader.notify(constant.EventTraderCrashed, fmt.Sprint(err))
 			}
-			if exit, err := trader.ctx.Get("exit"); err == nil && exit.IsFunction() {
-				if _, err := exit.Call(exit); err != nil {
-					trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+			trader.vm.clearInterrupt() //遗留的中断不能影响exit函数的执行
+			if trader.vm.has("exit") {
+				if _, err := trader.vm.call("exit"); err != nil {
+					trader.logError(err)
 				}
 			}
 			trader.Status = 0
 		}()
 		trader.LastRunAt = time.Now()
+		//保存本次运行的开始时间,清理日志时默认清理这之前的日志
+		if err := model.DB.Model(&trader.Trader).UpdateColumn("last_run_at", trader.LastRunAt).Error; err != nil {
+			trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Save the last run time error, ", err)
+		}
 		trader.Status = 1
-		if _, err := trader.ctx.Run(trader.Algorithm.Script); err != nil {
-			trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+		trader.limiter.begin()
+		go trader.limiter.watch(trader.vm)
+		if err := trader.vm.run(scriptFilename, trader.Algorithm.Script); err != nil {
+			trader.logError(err)
 		}
-		if main, err := trader.ctx.Get("main"); err != nil || !main.IsFunction() {
+		if !trader.vm.has("main") {
 			trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Can not get the main function")
 		} else {
-			if _, err := main.Call(main); err != nil {
-				trader.Logger.Log(constant.ERROR, "", 0.0, 0.0, err)
+			if _, err := trader.vm.call("main"); err != nil {
+				trader.logError(err)
+				trader.notify(constant.EventTraderCrashed, newScriptError(err, trader.Algorithm.Script).Message)
 			}
 		}
 	}()
-	Executor[trader.ID] = &trader
+	Executor[trader.ID] = trader
 	return
 }
 
@@ -150,7 +290,7 @@ func stop(id int64) (err error) {
 	if t, ok := Executor[id]; !ok || t == nil {
 		return fmt.Errorf("Can not found the Trader")
 	}
-	Executor[id].ctx.Interrupt <- func() { panic(errHalt) }
+	Executor[id].vm.interrupt(errHalt)
 	return
 }
 
@@ -0,0 +1,170 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/phonegapX/QuantBot/api"
+	"github.com/phonegapX/QuantBot/config"
+	"github.com/phonegapX/QuantBot/constant"
+)
+
+// Watchdog Variable
+var (
+	errHung              = fmt.Errorf("HUNG")
+	watchdogInterval     = 5 * time.Second
+	watchdogRestartRetry = 3 //自动重启连续失败的次数达到后不再重启
+)
+
+//策略的心跳,每次Sleep、交易所调用和打印日志时更新,休眠期间不会被认为卡死
+type heartbeat struct {
+	mutex    sync.Mutex
+	last     time.Time
+	sleeping bool
+	hung     bool //已经被看门狗中断
+	failures int  //看门狗自动重启失败的次数
+}
+
+func newHeartbeat() *heartbeat {
+	return &heartbeat{last: time.Now()}
+}
+
+func (h *heartbeat) beat() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.last = time.Now()
+}
+
+func (h *heartbeat) sleep(sleeping bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.last = time.Now()
+	h.sleeping = sleeping
+}
+
+//age 距离上一次心跳的时间,休眠期间为0
+func (h *heartbeat) age() time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.sleeping {
+		return 0
+	}
+	return time.Since(h.last)
+}
+
+// GetHeartbeatAge get the milliseconds since the last heartbeat of a running trader
+func GetHeartbeatAge(id int64) int64 {
+	executorMutex.Lock()
+	defer executorMutex.Unlock()
+	if t, ok := Executor[id]; ok && t != nil && t.Status > 0 {
+		return t.heartbeat.age().Nanoseconds() / 1000000
+	}
+	return 0
+}
+
+//bindExchange 把交易所的所有方法包装为js对象,每次调用交易所方法时更新心跳,GetOrder查询到完全成交的订单时发送通知
+func (g *Global) bindExchange(vm engine, e api.Exchange) (interface{}, error) {
+	methods := make(map[string]interface{})
+	value := reflect.ValueOf(e)
+	for i := 0; i < value.NumMethod(); i++ {
+		method := value.Method(i)
+		name := value.Type().Method(i).Name
+		fn := reflect.MakeFunc(method.Type(), func(args []reflect.Value) (results []reflect.Value) {
+			g.heartbeat.beat()
+			defer g.heartbeat.beat()
+			if method.Type().IsVariadic() {
+				results = method.CallSlice(args)
+			} else {
+				results = method.Call(args)
+			}
+			if name == "GetOrder" && len(results) > 0 {
+				g.notifyFilled(e, results[0].Interface())
+			}
+			return
+		})
+		methods[name] = fn.Interface()
+	}
+	return vm.object(methods)
+}
+
+//bindExchanges 绑定所有的交易所到js虚拟机
+func (g *Global) bindExchanges(vm engine) (err error) {
+	exchanges := []interface{}{}
+	for i, e := range g.es {
+		object, err := g.bindExchange(vm, e)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			vm.set("Exchange", object)
+			vm.set("E", object)
+		}
+		exchanges = append(exchanges, object)
+	}
+	array, err := vm.array(exchanges)
+	if err != nil {
+		return
+	}
+	vm.set("Exchanges", array)
+	vm.set("Es", array)
+	return
+}
+
+func init() {
+	go watchdog()
+}
+
+//watchdog 检查所有运行中策略的心跳,超时的策略会被中断,并可以自动重启
+func watchdog() {
+	for {
+		time.Sleep(watchdogInterval)
+		timeout := config.Int("watchdogtimeout", 0)
+		if timeout <= 0 {
+			continue
+		}
+		restart := config.String("watchdogrestart") == "true"
+		executorMutex.Lock()
+		for id, t := range Executor {
+			if t == nil {
+				continue
+			}
+			t.heartbeat.mutex.Lock()
+			hung := t.heartbeat.hung
+			t.heartbeat.mutex.Unlock()
+			if hung {
+				if t.Status <= 0 && restart {
+					if err := run(id); err != nil {
+						t.heartbeat.mutex.Lock()
+						t.heartbeat.failures++
+						failures := t.heartbeat.failures
+						//重启失败的次数过多时放弃,不再重复重启和打印日志
+						if failures >= watchdogRestartRetry {
+							t.heartbeat.hung = false
+						}
+						t.heartbeat.mutex.Unlock()
+						t.Logger.Log(constant.ERROR, "", 0.0, 0.0, "Watchdog restart error, ", err)
+						if failures >= watchdogRestartRetry {
+							t.Logger.Log(constant.ERROR, "", 0.0, 0.0, fmt.Sprintf("Watchdog gave up restarting the trader after %v failures", failures))
+						}
+					} else {
+						t.Logger.Log(constant.INFO, "", 0.0, 0.0, "Watchdog restarted the trader")
+					}
+				}
+				continue
+			}
+			if t.Status <= 0 || t.heartbeat.age() < time.Duration(timeout)*time.Second {
+				continue
+			}
+			t.heartbeat.mutex.Lock()
+			t.heartbeat.hung = true
+			t.heartbeat.mutex.Unlock()
+			t.Logger.Log(constant.ERROR, "", 0.0, 0.0, fmt.Sprintf("Watchdog: no heartbeat in %vs, interrupt the trader", timeout))
+			log.Printf("Trader %v has no heartbeat in %vs\n", id, timeout)
+			t.vm.interrupt(errHung)
+		}
+		executorMutex.Unlock()
+	}
+}